@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/basket"
+	"github.com/mattburman/tesco/pkg/export"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var basketName string
+var basketQuantity int
+var basketDays int
+var basketFormat string
+var basketOut string
+
+var basketCmd = &cobra.Command{
+	Use:   "basket",
+	Short: "build named baskets of stored products and total their price and macros",
+	Long: `Build named baskets of stored products, saved in the database. Baskets are priced with the offers
+  running now, and their nutrition totalled for the whole packs, or per day with --days.
+  --basket picks the basket, "default" if not given.
+  e.g. tesco basket add 300400483 --qty 2 --basket week
+       tesco basket show --basket week --days 7 -o table
+  `,
+}
+
+var basketAddCmd = &cobra.Command{
+	Use:   "add <id>",
+	Short: "add packs of a stored product to a basket",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		total, err := store.AddToBasket(db, basketName, args[0], basketQuantity)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("basket %v has %v of %v\n", basketName, total, args[0])
+		return nil
+	},
+}
+
+var basketRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "take packs of a product out of a basket, all of them unless --qty is given",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		quantity := 0
+		if cmd.Flags().Changed("qty") {
+			quantity = basketQuantity
+		}
+		return store.RemoveFromBasket(db, basketName, args[0], quantity)
+	},
+}
+
+var basketShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "show a basket with its total price and macros",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		totals, err := basketTotals()
+		if err != nil {
+			return err
+		}
+		if outputFormat == output.Table {
+			writeBasket(os.Stdout, totals)
+			return nil
+		}
+		raw, err := json.Marshal(totals)
+		if err != nil {
+			return fmt.Errorf("unable to marshal basket: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+var basketExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export a basket as a shopping list",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		for _, f := range basket.Formats {
+			if f == basketFormat {
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported basket format %q, must be one of: %v", basketFormat, strings.Join(basket.Formats, ", "))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		totals, err := basketTotals()
+		if err != nil {
+			return err
+		}
+		if basketOut == "" {
+			return basket.Write(os.Stdout, basketFormat, totals)
+		}
+		f, err := os.Create(basketOut)
+		if err != nil {
+			return fmt.Errorf("unable to create %v: %v", basketOut, err)
+		}
+		if err := basket.Write(f, basketFormat, totals); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	},
+}
+
+var basketListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list baskets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		baskets, err := store.Baskets(db)
+		if err != nil {
+			return err
+		}
+		if outputFormat == output.Table {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tPRODUCTS\tPACKS\tCREATED")
+			for _, b := range baskets {
+				fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", b.Name, b.Products, b.Packs, formatDate(b.CreatedAt))
+			}
+			return tw.Flush()
+		}
+		raw, err := json.Marshal(baskets)
+		if err != nil {
+			return fmt.Errorf("unable to marshal baskets: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+var basketDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "delete a basket and everything in it",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return store.DeleteBasket(db, basketName)
+	},
+}
+
+// basketTotals totals the --basket basket at today's offers
+func basketTotals() (basket.Totals, error) {
+	db, err := store.Open(dbPath)
+	if err != nil {
+		return basket.Totals{}, err
+	}
+	defer db.Close()
+	items, err := store.BasketItems(db, basketName)
+	if err != nil {
+		return basket.Totals{}, err
+	}
+	return basket.Total(items, time.Now(), basketDays), nil
+}
+
+// writeBasket writes a table of the lines of a basket followed by its totals
+func writeBasket(w io.Writer, totals basket.Totals) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tQTY\tPRICE\tOFFER\tKCAL\tPROTEIN\tCARBS\tFAT")
+	for _, l := range totals.Lines {
+		kcal, protein, carbs, fat := "-", "-", "-", "-"
+		if n := l.Nutrients; n != nil {
+			kcal, protein = fmt.Sprintf("%.0f", n.Kcal), fmt.Sprintf("%.1f", n.Protein)
+			carbs, fat = fmt.Sprintf("%.1f", n.Carbs), fmt.Sprintf("%.1f", n.Fat)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t£%.2f\t%v\t%v\t%v\t%v\t%v\n", l.ID, l.Name, l.Quantity, l.Price, dash(l.Offer), kcal, protein, carbs, fat)
+	}
+	n := totals.Nutrients
+	fmt.Fprintf(tw, "\tTOTAL\t\t£%.2f\t%v\t%.0f\t%.1f\t%.1f\t%.1f\n", totals.Price, savingText(totals.Saving), n.Kcal, n.Protein, n.Carbs, n.Fat)
+	if d := totals.PerDay; d != nil {
+		fmt.Fprintf(tw, "\tPER DAY (%v)\t\t£%.2f\t\t%.0f\t%.1f\t%.1f\t%.1f\n", totals.Days, totals.PricePerDay, d.Kcal, d.Protein, d.Carbs, d.Fat)
+	}
+	tw.Flush()
+	if len(totals.Missing) > 0 {
+		fmt.Fprintf(w, "\nno nutrition for whole packs of: %v\n", strings.Join(totals.Missing, ", "))
+	}
+}
+
+func savingText(saving float64) string {
+	if saving < 0.005 {
+		return "-"
+	}
+	return fmt.Sprintf("saving £%.2f", saving)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	basketCmd.PersistentFlags().StringVar(&basketName, "basket", "default", "name of the basket")
+	basketAddCmd.Flags().IntVar(&basketQuantity, "qty", 1, "number of packs")
+	basketRemoveCmd.Flags().IntVar(&basketQuantity, "qty", 1, "number of packs to take out")
+	basketShowCmd.Flags().IntVar(&basketDays, "days", 0, "number of days the basket is for, to show per-day figures")
+	basketExportCmd.Flags().StringVar(&basketFormat, "format", export.CSV, fmt.Sprintf("shopping list format, one of: %v", strings.Join(basket.Formats, ", ")))
+	basketExportCmd.Flags().StringVar(&basketOut, "out", "", "file to write to (default stdout)")
+	basketCmd.AddCommand(basketAddCmd, basketRemoveCmd, basketShowCmd, basketExportCmd, basketListCmd, basketDeleteCmd)
+	RootCmd.AddCommand(basketCmd)
+}
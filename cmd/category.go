@@ -3,12 +3,13 @@ package cmd
 import (
 	"fmt"
 	"github.com/mattburman/tesco/internal/category"
+	"github.com/mattburman/tesco/pkg/output"
 	"github.com/spf13/cobra"
 )
 
 var scrapeCategoryCmd = &cobra.Command{
 	Use:   "category <url>",
-	Short: "scrape category by URL and persist to data.db",
+	Short: "scrape category by URL and persist to the database",
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			return fmt.Errorf("No URL supplied")
@@ -17,7 +18,7 @@ var scrapeCategoryCmd = &cobra.Command{
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		url := args[0]
-		err := category.ScrapeToSqlite(url, concurrency)
+		err := category.ScrapeToSqlite(dbPath, url, concurrency)
 		if err != nil {
 			return err
 		}
@@ -40,8 +41,8 @@ var getCategoryCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		fmt.Println(*category)
-		return nil
+		rows := output.ListingRows(*category, "results.productItems.#.product")
+		return writeOutput(output.Document{JSON: *category, Rows: rows})
 	},
 }
 
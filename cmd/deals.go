@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/promotion"
+	"github.com/spf13/cobra"
+)
+
+var dealsFilter store.Filter
+var dealsKinds []string
+var dealsAll bool
+var dealsLimit int
+var dealsDiet dietFlags
+
+// dealJSON is a deal as written by the json and yaml formats
+type dealJSON struct {
+	ID                 string          `json:"id"`
+	Name               string          `json:"name"`
+	Offer              promotion.Offer `json:"offer"`
+	ShelfPrice         float64         `json:"shelfPrice"`
+	UnitPrice          float64         `json:"unitPrice"`
+	UnitOfMeasure      string          `json:"unitOfMeasure"`
+	EffectivePrice     *float64        `json:"effectivePrice"`
+	EffectiveUnitPrice *float64        `json:"effectiveUnitPrice"`
+	FirstSeen          time.Time       `json:"firstSeen"`
+	LastSeen           time.Time       `json:"lastSeen"`
+}
+
+var dealsCmd = &cobra.Command{
+	Use:   "deals",
+	Short: "list offers on stored products, such as multibuys and Clubcard prices",
+	Long: fmt.Sprintf(`List the offers running on stored products, biggest saving first, with the effective price of
+  one item when buying enough to get the offer. Offers are recorded each time a product is fetched,
+  and --all lists every offer ever seen, including ended ones.
+  Kinds of offer are: %v. --kind clubcard includes Clubcard multibuys.
+  e.g. tesco deals --kind clubcard,multibuy --category "Fresh Meat & Poultry"
+  `, joinKinds(promotion.Kinds)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kinds := []promotion.Kind{}
+		for _, name := range dealsKinds {
+			kind, ok := promotion.ParseKind(name)
+			if !ok {
+				return fmt.Errorf("unknown offer kind %q, must be one of: %v", name, joinKinds(promotion.Kinds))
+			}
+			kinds = append(kinds, kind)
+		}
+		if err := dealsDiet.apply(&dealsFilter); err != nil {
+			return err
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		at := time.Now()
+		if dealsAll {
+			at = time.Time{}
+		}
+		deals, err := store.Deals(db, dealsFilter, at, kinds)
+		if err != nil {
+			return err
+		}
+		if dealsLimit > 0 && len(deals) > dealsLimit {
+			deals = deals[:dealsLimit]
+		}
+
+		rows := make([]output.Row, len(deals))
+		records := make([]dealJSON, len(deals))
+		for i, deal := range deals {
+			rows[i] = output.ProductRow(deal.Product)
+			rows[i].Price = deal.ShelfPrice
+			rows[i].Score = deal.EffectivePrice
+			rows[i].Offer = describeOffer(deal.Offer)
+			records[i] = dealJSON{
+				ID:                 deal.ID(),
+				Name:               deal.Name(),
+				Offer:              deal.Offer,
+				ShelfPrice:         deal.ShelfPrice,
+				UnitPrice:          deal.UnitPrice,
+				UnitOfMeasure:      deal.UnitOfMeasure(),
+				EffectivePrice:     deal.EffectivePrice,
+				EffectiveUnitPrice: deal.EffectiveUnitPrice,
+				FirstSeen:          deal.FirstSeen,
+				LastSeen:           deal.LastSeen,
+			}
+		}
+		raw, err := json.Marshal(records)
+		if err != nil {
+			return fmt.Errorf("unable to marshal deals: %v", err)
+		}
+		return writeOutput(output.Document{JSON: string(raw), Rows: rows, Score: "deal price"})
+	},
+}
+
+// describeOffer returns the offer text with its kind and end date, e.g.
+// "Any 2 for £5.00 (multibuy, until 2019-12-30)"
+func describeOffer(o promotion.Offer) string {
+	details := []string{string(o.Kind)}
+	if !o.End.IsZero() {
+		details = append(details, "until "+o.End.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("%v (%v)", o.Text, strings.Join(details, ", "))
+}
+
+func joinKinds(kinds []promotion.Kind) string {
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		names[i] = string(kind)
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	dealsCmd.Flags().StringSliceVar(&dealsFilter.Categories, "category", nil, "only list offers in these departments, aisles or shelves")
+	dealsCmd.Flags().StringSliceVar(&dealsFilter.Brands, "brand", nil, "only list offers on these brands")
+	dealsCmd.Flags().StringSliceVar(&dealsKinds, "kind", nil, "only list these kinds of offer, e.g. clubcard,multibuy")
+	dealsCmd.Flags().BoolVar(&dealsAll, "all", false, "list every offer ever seen rather than those running now")
+	dealsCmd.Flags().IntVar(&dealsLimit, "limit", 50, "number of offers to show, 0 for all")
+	addDietFlags(dealsCmd, &dealsDiet)
+	RootCmd.AddCommand(dealsCmd)
+}
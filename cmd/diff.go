@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var diffSince time.Duration
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [id]",
+	Short: "show how stored products changed between fetches",
+	Long: `Show field-level changes in title, price, pack size, nutrients and ingredients between the
+  snapshots kept each time a product is fetched and has changed, to spot reformulations and shrinkflation.
+  With an ID every change to that product is shown, otherwise changes to all products fetched within --since.
+  e.g. tesco diff 300400483
+       tesco diff --since 168h -o table
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var changes []store.ProductChange
+		if len(args) > 0 {
+			changes, err = store.History(db, args[0])
+		} else {
+			changes, err = store.Changes(db, time.Now().Add(-diffSince))
+		}
+		if err != nil {
+			return err
+		}
+		if outputFormat == output.Table {
+			writeChanges(os.Stdout, changes)
+			return nil
+		}
+		raw, err := json.Marshal(changes)
+		if err != nil {
+			return fmt.Errorf("unable to marshal changes: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+// writeChanges writes a table of each field change
+func writeChanges(w io.Writer, changes []store.ProductChange) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tFROM\tTO\tFIELD\tOLD\tNEW")
+	for _, change := range changes {
+		for _, c := range change.Changes {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", change.ProductID, change.Name,
+				formatDate(change.From), formatDate(change.To), c.Field, truncate(c.Old, 60), truncate(c.New, 60))
+		}
+	}
+	tw.Flush()
+}
+
+// reportChanges writes the changes to products fetched since a scrape run started
+func reportChanges(since time.Time) error {
+	db, err := store.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	changes, err := store.Changes(db, since)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+	fmt.Printf("\n%v changed products\n", len(changes))
+	writeChanges(os.Stdout, changes)
+	return nil
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+func init() {
+	diffCmd.Flags().DurationVar(&diffSince, "since", 24*time.Hour, "without an ID, show changes fetched within this long")
+	RootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/export"
+	"github.com/spf13/cobra"
+)
+
+var exportFormat string
+var exportOut string
+var exportUpdatedSince string
+var exportFilter store.Filter
+var exportDiet dietFlags
+
+// rawFormat dumps the stored payloads for tesco import rather than flattened products
+const rawFormat = "raw"
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export stored products as flattened nutrients and prices",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		for _, f := range append(export.Formats, rawFormat) {
+			if f == exportFormat {
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported export format %q, must be one of: %v, %v", exportFormat, strings.Join(export.Formats, ", "), rawFormat)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := exportFilter
+		if err := exportDiet.apply(&filter); err != nil {
+			return err
+		}
+		if exportUpdatedSince != "" {
+			since, err := parseTime(exportUpdatedSince)
+			if err != nil {
+				return err
+			}
+			filter.UpdatedSince = since
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		write := func(w io.Writer) error {
+			if exportFormat == rawFormat {
+				return store.Dump(db, w, filter)
+			}
+			products, err := store.Products(db, filter)
+			if err != nil {
+				return err
+			}
+			records := make([]export.Record, len(products))
+			for i, p := range products {
+				records[i] = export.NewRecord(p.Product, p.FetchedAt)
+			}
+			return export.Write(w, exportFormat, records)
+		}
+		if exportOut == "" {
+			return write(os.Stdout)
+		}
+		f, err := os.Create(exportOut)
+		if err != nil {
+			return fmt.Errorf("unable to create %v: %v", exportOut, err)
+		}
+		if err := write(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	},
+}
+
+// parseTime parses a date such as 2019-11-05 or an RFC3339 timestamp
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%v is not a date (2006-01-02) or RFC3339 timestamp", s)
+	}
+	return t, nil
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", export.CSV, fmt.Sprintf("export format, one of: %v, or %v to dump payloads for import", strings.Join(export.Formats, ", "), rawFormat))
+	exportCmd.Flags().StringVar(&exportOut, "out", "", "file to write to (default stdout)")
+	exportCmd.Flags().StringSliceVar(&exportFilter.Categories, "category", nil, "only export products in these departments, aisles or shelves")
+	exportCmd.Flags().StringSliceVar(&exportFilter.Brands, "brand", nil, "only export products of these brands")
+	exportCmd.Flags().StringVar(&exportUpdatedSince, "updated-since", "", "only export products fetched on or after this date or RFC3339 timestamp")
+	addDietFlags(exportCmd, &exportDiet)
+	RootCmd.AddCommand(exportCmd)
+}
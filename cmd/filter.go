@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/allergens"
+	"github.com/spf13/cobra"
+)
+
+// dietFlags holds the --exclude-allergen and --diet flags of a command
+type dietFlags struct {
+	excludeAllergens []string
+	diets            []string
+}
+
+// addDietFlags adds --exclude-allergen and --diet to cmd
+func addDietFlags(cmd *cobra.Command, flags *dietFlags) {
+	cmd.Flags().StringSliceVar(&flags.excludeAllergens, "exclude-allergen", nil, "exclude products that contain or may contain these allergens, e.g. milk,nuts")
+	cmd.Flags().StringSliceVar(&flags.diets, "diet", nil, "only include products labelled suitable for these diets: vegetarian, vegan, gluten-free, dairy-free")
+}
+
+// apply validates the flags and sets them on filter
+func (flags dietFlags) apply(filter *store.Filter) error {
+	filter.ExcludeAllergens = nil
+	for _, name := range flags.excludeAllergens {
+		allergen, err := allergens.Lookup(name)
+		if err != nil {
+			return err
+		}
+		filter.ExcludeAllergens = append(filter.ExcludeAllergens, allergen)
+	}
+	filter.Diets = nil
+	for _, name := range flags.diets {
+		diet, err := allergens.ParseDiet(name)
+		if err != nil {
+			return err
+		}
+		filter.Diets = append(filter.Diets, diet)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/gtin"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/search"
+	"github.com/spf13/cobra"
+)
+
+var gtinOffline bool
+
+var getGTINCmd = &cobra.Command{
+	Use:   "gtin <barcode>",
+	Short: "get product by GTIN barcode, from the database or else a live search",
+	Long: `Get a product by the GTIN-8, GTIN-12 (UPC), GTIN-13 (EAN) or GTIN-14 number under its barcode.
+  The check digit is validated and missing leading zeros are tolerated. Products already in the
+  database are returned without a request, otherwise Tesco is searched for the barcode.
+  `,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("No barcode supplied")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		code, err := gtin.Normalize(args[0])
+		if err != nil {
+			return err
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		stored, err := store.ByGTIN(db, code)
+		if err != nil {
+			return err
+		}
+		if len(stored) == 1 {
+			return writeOutput(productDocument(stored[0].Product, stored[0].Raw()))
+		}
+		if len(stored) > 1 {
+			rows := make([]output.Row, len(stored))
+			for i, s := range stored {
+				rows[i] = output.ProductRow(s.Product)
+			}
+			return writeOutput(output.Document{Rows: rows})
+		}
+		if gtinOffline {
+			return fmt.Errorf("no product with GTIN %v in the database", code)
+		}
+
+		p, data, err := search.ByGTIN(code)
+		if err != nil {
+			return fmt.Errorf("failed to search for GTIN %v: %v", code, err)
+		}
+		if p == nil {
+			return fmt.Errorf("no product found with GTIN %v", code)
+		}
+		return writeOutput(productDocument(p, *data))
+	},
+}
+
+func init() {
+	getGTINCmd.Flags().BoolVar(&gtinOffline, "offline", false, "only look in the database, never search Tesco")
+	GetCmd.AddCommand(getGTINCmd)
+}
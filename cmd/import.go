@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "import JSON Lines of raw product payloads into the database",
+	Long: `Import reads JSON Lines of {"id", "url", "raw", "fetched_at"} records, as written by
+  export --format raw, from a file or stdin. Each record is validated as a product before
+  being upserted, unless a later fetch of the product is already stored. Rejected records
+  are reported on stderr.
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var r io.Reader = os.Stdin
+		if len(args) > 0 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("unable to open %v: %v", args[0], err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		result, err := store.Import(db, r)
+		for _, reject := range result.Rejects {
+			fmt.Fprintf(os.Stderr, "rejected %v\n", reject)
+		}
+		fmt.Fprintf(os.Stderr, "imported %v products, skipped %v older than those stored, rejected %v\n",
+			result.Imported, result.Older, len(result.Rejects))
+		if err != nil {
+			return err
+		}
+		if len(result.Rejects) > 0 {
+			return fmt.Errorf("%v records were rejected", len(result.Rejects))
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mattburman/tesco/pkg/output"
+)
+
+var outputFormat string
+
+// writeOutput writes doc to stdout in the format chosen with --output
+func writeOutput(doc output.Document) error {
+	if err := output.Validate(outputFormat); err != nil {
+		return err
+	}
+	return output.Write(os.Stdout, outputFormat, doc)
+}
+
+// writeJSONOutput writes raw to stdout for commands whose results are not products, so
+// have no rows for csv. Such commands write their own table before calling it.
+func writeJSONOutput(raw string) error {
+	if outputFormat == output.CSV {
+		return fmt.Errorf("unsupported output format %v for this command", outputFormat)
+	}
+	return writeOutput(output.Document{JSON: raw})
+}
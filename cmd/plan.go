@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/plan"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var planFilter store.Filter
+var planDiet dietFlags
+var planTargets = map[string]*string{"kcal": new(string), "protein": new(string), "carbs": new(string), "fat": new(string)}
+var planOptions plan.Options
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "plan the cheapest daily servings of stored products that hit macro targets",
+	Long: `Plan whole daily servings of stored products that hit daily energy and macro targets at the lowest
+  cost, and the packs to buy for the week. Targets are min:max, min: or :max, and may be set in the config
+  file as plan.kcal, plan.protein, plan.carbs and plan.fat. Products without nutrition, a serving size or
+  servings per pack are left out. --budget is the most to spend on the servings eaten over all days,
+  so buying whole packs of them may cost more.
+  e.g. tesco plan --kcal 1800:2200 --protein 150: --fat :70 --exclude-allergen milk --max-servings 3 -o table
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targets := map[string]*plan.Range{
+			"kcal":    &planOptions.Kcal,
+			"protein": &planOptions.Protein,
+			"carbs":   &planOptions.Carbs,
+			"fat":     &planOptions.Fat,
+		}
+		for name, target := range targets {
+			value := *planTargets[name]
+			if !cmd.Flags().Changed(name) {
+				value = viper.GetString("plan." + name)
+			}
+			r, err := plan.ParseRange(value)
+			if err != nil {
+				return fmt.Errorf("invalid --%v: %v", name, err)
+			}
+			*target = r
+		}
+		if err := planDiet.apply(&planFilter); err != nil {
+			return err
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		stored, err := store.Products(db, planFilter)
+		if err != nil {
+			return err
+		}
+		products := make([]*product.Product, len(stored))
+		for i, s := range stored {
+			products[i] = s.Product
+		}
+		result, err := plan.Make(products, planOptions)
+		if err != nil {
+			return err
+		}
+		if !result.Optimal {
+			fmt.Fprintf(os.Stderr, "stopped searching after %v nodes, the plan may not be the cheapest\n", planOptions.MaxNodes)
+		}
+
+		if outputFormat == output.Table {
+			fmt.Printf("DAILY %.0f kcal, %.1fg protein, %.1fg carbs, %.1fg fat\n", result.Daily.Kcal, result.Daily.Protein, result.Daily.Carbs, result.Daily.Fat)
+			fmt.Printf("COST £%.2f eaten, £%.2f to buy over %v days\n\n", result.ServingCost, result.ShoppingCost, result.Days)
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tSERVING\tPER DAY\tSERVINGS\tPACKS\tCOST")
+			for _, l := range result.Lines {
+				fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t£%.2f\n", l.ID, l.Name, l.Serving, l.ServingsPerDay, l.Servings, l.Packs, l.Cost)
+			}
+			return tw.Flush()
+		}
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("unable to marshal plan: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+func init() {
+	for _, name := range []string{"kcal", "protein", "carbs", "fat"} {
+		planCmd.Flags().StringVar(planTargets[name], name, "", fmt.Sprintf("daily %v target as min:max, min: or :max", name))
+	}
+	planCmd.Flags().IntVar(&planOptions.Days, "days", 7, "number of days to plan")
+	planCmd.Flags().IntVar(&planOptions.MaxServings, "max-servings", 3, "most servings of one product a day, 0 for no limit")
+	planCmd.Flags().Float64Var(&planOptions.Budget, "budget", 0, "most to spend in pounds on the servings eaten over all days, not the whole packs bought, 0 for no limit")
+	planCmd.Flags().IntVar(&planOptions.MaxNodes, "max-nodes", 5000, "stop searching for a cheaper plan after this many nodes")
+	planCmd.Flags().StringSliceVar(&planFilter.Categories, "category", nil, "only plan products in these departments, aisles or shelves")
+	planCmd.Flags().StringSliceVar(&planFilter.Brands, "brand", nil, "only plan products of these brands")
+	addDietFlags(planCmd, &planDiet)
+	RootCmd.AddCommand(planCmd)
+}
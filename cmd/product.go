@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/mattburman/tesco/pkg/nutrition"
+	"github.com/mattburman/tesco/pkg/output"
 	"github.com/mattburman/tesco/pkg/product"
 	"strconv"
 
@@ -30,11 +32,27 @@ var productCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to get product: %v", err)
 		}
-		fmt.Println(*data)
-		return nil
+		p, err := product.NewProduct(*data, product.IDToURL(productID))
+		if err != nil {
+			return fmt.Errorf("failed to parse product: %v", err)
+		}
+		return writeOutput(productDocument(p, *data))
 	},
 }
 
+// productDocument returns the output of a single product with its parsed ingredients, health
+// and pack totals
+func productDocument(p *product.Product, data string) output.Document {
+	doc := output.Document{JSON: data, Rows: []output.Row{output.ProductRow(p)}, Ingredients: p.Ingredients()}
+	if health, ok := nutrition.Assess(p); ok {
+		doc.Health = &health
+	}
+	if totals, ok := nutrition.Total(p); ok {
+		doc.Totals = &totals
+	}
+	return doc
+}
+
 func init() {
 	GetCmd.AddCommand(productCmd)
 }
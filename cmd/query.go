@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/query"
+	"github.com/spf13/cobra"
+)
+
+var querySort string
+var queryDescending bool
+var queryLimit int
+var queryDiet dietFlags
+
+var queryCmd = &cobra.Command{
+	Use:   "query <expr>",
+	Short: "query stored products with a filter expression",
+	Long: fmt.Sprintf(`Query stored products with a filter expression, e.g.
+  tesco query 'protein>20 AND kcal<200 AND aisle="Yoghurts" AND NOT allergen:milk'
+
+  Comparisons are field op value, with op one of = != < <= > >= or ~ (contains), over: %v.
+  price is the shelf price, unitprice the price per unit of measure and price100 the price per 100g.
+  grade is the Nutri-Score A to E and nutriscore its points, lower being healthier, and fatlight,
+  satlight, sugarlight and saltlight the front-of-pack traffic lights per 100g: green, amber or red.
+  packsize is the pack contents in g or ml, packkcal and packprotein the whole pack's energy and protein,
+  servingkcal and servingprotein those of a serving, and servingcost the shelf price divided by the servings.
+  rating is the average customer rating out of 5 and reviews the number of reviews.
+  Tags are kind:value, e.g. allergen:milk, may-contain:nuts, free-from:gluten, diet:vegan, brand:tesco, aisle:yoghurts.
+  Combine them with AND, OR, NOT and parentheses.
+  `, strings.Join(query.FieldNames(), ", ")),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("No query expression supplied")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		where, whereArgs, err := query.Compile(strings.Join(args, " "))
+		if err != nil {
+			return fmt.Errorf("invalid query: %v", err)
+		}
+		var filter store.Filter
+		if err := queryDiet.apply(&filter); err != nil {
+			return err
+		}
+		tagCondition, tagArgs := filter.TagCondition()
+		where = fmt.Sprintf("%v AND %v", where, tagCondition)
+		whereArgs = append(whereArgs, tagArgs...)
+		orderBy := ""
+		if querySort != "" {
+			orderBy, err = query.OrderBy(querySort, queryDescending)
+			if err != nil {
+				return err
+			}
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		products, err := store.Query(db, where, whereArgs, orderBy, queryLimit)
+		if err != nil {
+			return err
+		}
+		rows := make([]output.Row, len(products))
+		for i, p := range products {
+			rows[i] = output.ProductRow(p.Product)
+		}
+		return writeOutput(output.Document{Rows: rows})
+	},
+}
+
+func init() {
+	queryCmd.Flags().StringVar(&querySort, "sort", "", "field to sort by")
+	queryCmd.Flags().BoolVar(&queryDescending, "desc", false, "sort descending")
+	queryCmd.Flags().IntVar(&queryLimit, "limit", 0, "maximum number of products, 0 for all")
+	addDietFlags(queryCmd, &queryDiet)
+	RootCmd.AddCommand(queryCmd)
+}
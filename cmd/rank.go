@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/rank"
+	"github.com/spf13/cobra"
+)
+
+var rankFilter store.Filter
+var rankMin map[string]string
+var rankMax map[string]string
+var rankLimit int
+var rankAscending bool
+var rankByWeight bool
+var rankDiet dietFlags
+
+var rankCmd = &cobra.Command{
+	Use:   "rank <metric>",
+	Short: "rank stored products by a metric such as protein/kcal",
+	Long: fmt.Sprintf(`Rank stored products by a metric expression using + - * / and parentheses over: %v.
+  Nutrients are per 100g, or 100ml for liquids, and price is pounds per 100g or 100ml, so protein/price
+  is grams of protein per pound. --by-weight compares oils, milks, juices and other known liquids per 100g.
+  e.g. tesco rank protein/kcal --category "Fresh Meat & Poultry" --min protein=20 --limit 10
+  `, strings.Join(rank.Variables, ", ")),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("No metric supplied")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expr := strings.Join(args, " ")
+		metric, err := rank.Parse(expr, rank.Variables)
+		if err != nil {
+			return fmt.Errorf("invalid metric: %v", err)
+		}
+		min, err := parseFloats(rankMin)
+		if err != nil {
+			return fmt.Errorf("invalid --min: %v", err)
+		}
+		max, err := parseFloats(rankMax)
+		if err != nil {
+			return fmt.Errorf("invalid --max: %v", err)
+		}
+		thresholds, err := rank.NewThresholds(min, max)
+		if err != nil {
+			return err
+		}
+
+		if err := rankDiet.apply(&rankFilter); err != nil {
+			return err
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		stored, err := store.Products(db, rankFilter)
+		if err != nil {
+			return err
+		}
+		products := make([]*product.Product, len(stored))
+		for i, s := range stored {
+			products[i] = s.Product
+		}
+
+		results := rank.Rank(products, metric, thresholds, rankLimit, rankAscending, rankByWeight)
+		rows := make([]output.Row, len(results))
+		for i, result := range results {
+			value := result.Value
+			rows[i] = output.ProductRow(result.Product)
+			rows[i].Score = &value
+		}
+		return writeOutput(output.Document{Rows: rows, Score: expr})
+	},
+}
+
+// parseFloats parses the values of a name=value flag as floats
+func parseFloats(values map[string]string) (map[string]float64, error) {
+	floats := make(map[string]float64, len(values))
+	for name, value := range values {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v=%v is not a number", name, value)
+		}
+		floats[name] = f
+	}
+	return floats, nil
+}
+
+func init() {
+	rankCmd.Flags().StringSliceVar(&rankFilter.Categories, "category", nil, "only rank products in these departments, aisles or shelves")
+	rankCmd.Flags().StringSliceVar(&rankFilter.Brands, "brand", nil, "only rank products of these brands")
+	rankCmd.Flags().StringToStringVar(&rankMin, "min", nil, "minimum values, e.g. protein=20,kcal=50")
+	rankCmd.Flags().StringToStringVar(&rankMax, "max", nil, "maximum values, e.g. fat=5")
+	rankCmd.Flags().IntVar(&rankLimit, "limit", 20, "number of products to show, 0 for all")
+	rankCmd.Flags().BoolVar(&rankAscending, "asc", false, "rank lowest first, e.g. for fat/protein")
+	rankCmd.Flags().BoolVar(&rankByWeight, "by-weight", false, "compare known liquids per 100g using their density rather than per 100ml")
+	addDietFlags(rankCmd, &rankDiet)
+	RootCmd.AddCommand(rankCmd)
+}
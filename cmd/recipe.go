@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/nutrition"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/recipe"
+	"github.com/spf13/cobra"
+)
+
+var recipeCmd = &cobra.Command{
+	Use:   "recipe",
+	Short: "work out the nutrition and cost of recipes made from stored products",
+}
+
+var recipeAnalyzeCmd = &cobra.Command{
+	Use:   "analyze <recipe.yaml>",
+	Short: "total the macros and cost of a recipe, and per portion",
+	Long: `Total the macros and cost of a recipe, and per portion, from each product's nutrition per 100g or
+  100ml and unit price. A recipe is a YAML file of ingredients, each a stored product ID or a search
+  term, which picks the best match, and an amount in grams or ml:
+    name: Chilli con carne
+    portions: 6
+    ingredients:
+      - product: "254918073"
+        grams: 500
+      - product: red kidney beans
+        grams: 400
+  Ingredients without nutrition or a price are warned about on stderr and left out of the totals.
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("unable to open recipe: %v", err)
+		}
+		defer f.Close()
+		r, err := recipe.Parse(f)
+		if err != nil {
+			return err
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		analysis, err := recipe.Analyze(r, storedProduct(db))
+		if err != nil {
+			return err
+		}
+		for _, warning := range analysis.Warnings {
+			fmt.Fprintln(os.Stderr, "warning:", warning)
+		}
+
+		if outputFormat == output.Table {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tAMOUNT\tCOST\tKCAL\tPROTEIN\tCARBS\tFAT")
+			for _, l := range analysis.Lines {
+				cost := "-"
+				if l.Cost != nil {
+					cost = fmt.Sprintf("£%.2f", *l.Cost)
+				}
+				fmt.Fprintf(tw, "%v\t%v\t%v%v\t%v\t%v\n", l.ID, l.Name, l.Amount, l.Unit, cost, formatMacros(l.Nutrients))
+			}
+			fmt.Fprintf(tw, "\tTOTAL\t\t£%.2f\t%v\n", analysis.Cost, formatMacros(&analysis.Total))
+			fmt.Fprintf(tw, "\tPER PORTION (%v)\t\t£%.2f\t%v\n", analysis.Portions, analysis.CostPerPortion, formatMacros(&analysis.PerPortion))
+			return tw.Flush()
+		}
+		raw, err := json.Marshal(analysis)
+		if err != nil {
+			return fmt.Errorf("unable to marshal recipe: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+// storedProduct resolves a product ID, or else the best full-text match of a search term,
+// to a stored product
+func storedProduct(db *sql.DB) recipe.Resolver {
+	return func(ref string) (*product.Product, error) {
+		if _, err := strconv.Atoi(ref); err == nil {
+			products, err := store.Query(db, "f.product_id = ?", []interface{}{ref}, "", 1)
+			if err != nil {
+				return nil, err
+			}
+			if len(products) == 0 {
+				return nil, fmt.Errorf("product %v is not stored, scrape it first", ref)
+			}
+			return products[0].Product, nil
+		}
+		results, err := store.Search(db, ref, store.Filter{}, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return nil, fmt.Errorf("no stored product matches")
+		}
+		return results[0].Product, nil
+	}
+}
+
+// formatMacros returns tab separated kcal, protein, carbs and fat, or dashes if unknown
+func formatMacros(n *nutrition.Nutrients) string {
+	if n == nil {
+		return "-\t-\t-\t-"
+	}
+	return fmt.Sprintf("%.0f\t%.1f\t%.1f\t%.1f", n.Kcal, n.Protein, n.Carbs, n.Fat)
+}
+
+func init() {
+	recipeCmd.AddCommand(recipeAnalyzeCmd)
+	RootCmd.AddCommand(recipeCmd)
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/ownlabel"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/spf13/cobra"
+)
+
+var shrinkflationSince time.Duration
+var ownLabelFilter store.Filter
+var ownLabelBrands []string
+var ownLabelMinSimilarity float64
+
+var reportCmd = &cobra.Command{
+	Use:   "report <type>",
+	Short: "analyse stored products and their history",
+}
+
+var shrinkflationCmd = &cobra.Command{
+	Use:   "shrinkflation",
+	Short: "list products whose pack shrank while the price stayed flat or rose",
+	Long: `List products whose net contents went down between two stored snapshots while the price stayed the
+  same or rose, largest rise in price per g or ml first. Only stored history is used, so products must
+  have been fetched more than once.
+  e.g. tesco report shrinkflation --since 8760h -o table
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var since time.Time
+		if shrinkflationSince > 0 {
+			since = time.Now().Add(-shrinkflationSince)
+		}
+		shrinks, err := store.Shrinkflation(db, since)
+		if err != nil {
+			return err
+		}
+		if outputFormat == output.Table {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tFROM\tTO\tSIZE\tPRICE\tUNIT PRICE")
+			for _, s := range shrinks {
+				fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v%v → %v%v\t£%.2f → £%.2f\t+%.1f%%\n", s.ProductID, s.Name,
+					formatDate(s.From), formatDate(s.To), s.OldSize, s.Unit, s.NewSize, s.Unit, s.OldPrice, s.NewPrice, s.UnitPriceIncrease*100)
+			}
+			return tw.Flush()
+		}
+		raw, err := json.Marshal(shrinks)
+		if err != nil {
+			return fmt.Errorf("unable to marshal shrinkflation: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+var ownLabelCmd = &cobra.Command{
+	Use:   "own-label",
+	Short: "compare own-label products with branded equivalents on the same shelf",
+	Long: `Pair each own-label product with the branded product on the same shelf whose name is most alike,
+  leaving out brands and pack sizes, and compare their price per 100g, 100ml or unit and macros per 100g
+  side by side, largest saving first. Each aisle is summarised by how many own-label products are cheaper
+  and the average saving.
+  e.g. tesco report own-label --category "Tins & Cans" -o table
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		stored, err := store.Products(db, ownLabelFilter)
+		if err != nil {
+			return err
+		}
+		products := make([]*product.Product, len(stored))
+		for i, s := range stored {
+			products[i] = s.Product
+		}
+		report := ownlabel.Compare(products, ownlabel.Options{Brands: ownLabelBrands, MinSimilarity: ownLabelMinSimilarity})
+
+		if outputFormat == output.Table {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "SHELF\tNAME\tBRAND\tPER\tPRICE\tSAVING\tKCAL\tPROTEIN\tCARBS\tFAT")
+			for _, p := range report.Pairs {
+				fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t£%.2f\t%.0f%%\t%v\n", p.Shelf, truncate(p.OwnLabel.Name, 40), p.OwnLabel.Brand,
+					p.Unit, p.OwnLabel.UnitPrice, p.Saving*100, formatMacros(p.OwnLabel.Per100))
+				fmt.Fprintf(tw, "\t%v\t%v\t%v\t£%.2f\t\t%v\n", truncate(p.Branded.Name, 40), p.Branded.Brand, p.Unit, p.Branded.UnitPrice, formatMacros(p.Branded.Per100))
+			}
+			fmt.Fprintln(tw)
+			fmt.Fprintln(tw, "AISLE\tPAIRS\tCHEAPER\tDEARER\tAVERAGE SAVING")
+			for _, a := range report.Aisles {
+				fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%.0f%%\n", a.Aisle, a.Pairs, a.Cheaper, a.Dearer, a.AverageSaving*100)
+			}
+			return tw.Flush()
+		}
+		raw, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("unable to marshal own-label report: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+func init() {
+	ownLabelCmd.Flags().StringSliceVar(&ownLabelFilter.Categories, "category", nil, "only compare products in these departments, aisles or shelves")
+	ownLabelCmd.Flags().StringSliceVar(&ownLabelBrands, "own-brand", ownlabel.DefaultBrands, "brands that count as own-label, including brands starting with them")
+	ownLabelCmd.Flags().Float64Var(&ownLabelMinSimilarity, "min-similarity", ownlabel.DefaultMinSimilarity, "least share of name words, from 0 to 1, a pair must have in common")
+	reportCmd.AddCommand(ownLabelCmd)
+	shrinkflationCmd.Flags().DurationVar(&shrinkflationSince, "since", 0, "only include shrinks seen within this long, 0 for all history")
+	reportCmd.AddCommand(shrinkflationCmd)
+	RootCmd.AddCommand(reportCmd)
+}
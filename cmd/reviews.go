@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/review"
+	"github.com/spf13/cobra"
+)
+
+var reviewsLimit int
+
+// reviewsJSON is the reviews of a product as written by the json and yaml formats
+type reviewsJSON struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Stats   *review.Stats   `json:"stats"`
+	Reviews []review.Review `json:"reviews"`
+}
+
+var reviewsCmd = &cobra.Command{
+	Use:   "reviews <id>",
+	Short: "list the customer reviews of a stored product",
+	Long: `List the customer reviews of a stored product, newest first, with its average rating. Each fetch
+  only returns the latest reviews, so reviews are kept from every fetch of the product.
+  e.g. tesco reviews 300400483 -o table
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		products, err := store.Query(db, "f.product_id = ?", []interface{}{args[0]}, "", 1)
+		if err != nil {
+			return err
+		}
+		if len(products) == 0 {
+			return fmt.Errorf("product %v is not stored, scrape it first", args[0])
+		}
+		reviews, err := store.Reviews(db, args[0])
+		if err != nil {
+			return err
+		}
+		doc := reviewsJSON{ID: products[0].ID(), Name: products[0].Name(), Reviews: reviews}
+		if _, stats, ok := review.FromProduct(products[0].Product); ok {
+			doc.Stats = &stats
+		}
+		if reviewsLimit > 0 && len(doc.Reviews) > reviewsLimit {
+			doc.Reviews = doc.Reviews[:reviewsLimit]
+		}
+
+		if outputFormat == output.Table {
+			if doc.Stats != nil {
+				fmt.Printf("%v (%v): rated %.1f/%v from %v reviews\n\n", doc.Name, doc.ID, doc.Stats.Rating, doc.Stats.Range, doc.Stats.Count)
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "RATING\tDATE\tSUMMARY\tREVIEW")
+			for _, r := range doc.Reviews {
+				fmt.Fprintf(tw, "%v/%v\t%v\t%v\t%v\n", r.Rating, r.Range, formatDate(r.Submitted), truncate(r.Summary, 40), truncate(r.Text, 80))
+			}
+			return tw.Flush()
+		}
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("unable to marshal reviews: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+func init() {
+	reviewsCmd.Flags().IntVar(&reviewsLimit, "limit", 0, "number of reviews to show, 0 for all")
+	RootCmd.AddCommand(reviewsCmd)
+}
@@ -2,14 +2,18 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
 	"github.com/spf13/cobra"
 	"os"
+	"strings"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
+var dbPath string
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -40,6 +44,8 @@ func init() {
 	// will be global for your application.
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.product.yaml)")
+	RootCmd.PersistentFlags().StringVar(&dbPath, "db", store.DefaultPath, "sqlite3 database to persist and query products")
+	RootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", output.JSON, fmt.Sprintf("output format, one of: %v", strings.Join(output.Formats, ", ")))
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -68,6 +74,6 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
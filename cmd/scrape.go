@@ -1,17 +1,38 @@
 package cmd
 
 import (
+	"time"
+
+	"github.com/mattburman/tesco/pkg/metrics"
 	"github.com/spf13/cobra"
 )
 
 var concurrency int
+var metricsAddr string
+var scrapeStarted time.Time
 
 var ScrapeCmd = &cobra.Command{
 	Use:   "scrape <type>",
 	Short: "scrape tesco urls and persist the data",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		scrapeStarted = time.Now()
+		if metricsAddr == "" {
+			return nil
+		}
+		return metrics.Serve(metricsAddr)
+	},
+	// report what changed in the products fetched by the run, and alert on watched ones
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if err := reportChanges(scrapeStarted); err != nil {
+			return err
+		}
+		return checkWatches()
+	},
 }
 
 func init() {
 	ScrapeCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 3, "number of simultaneous requests")
+	ScrapeCmd.PersistentFlags().StringVar(&alertTarget, "alert", "", "send watch alerts to a webhook URL or append them to a file, rather than stdout")
+	ScrapeCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "address to expose Prometheus metrics on, e.g. :9090 (disabled if empty)")
 	RootCmd.AddCommand(ScrapeCmd)
 }
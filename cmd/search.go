@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mattburman/tesco/internal/category"
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/progress"
+	"github.com/mattburman/tesco/pkg/search"
+	"github.com/spf13/cobra"
+)
+
+var searchLimit int
+var searchDiet dietFlags
+var getSearchPages int
+var scrapeSearchPages int
+
+func requireTerms(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("No search terms supplied")
+	}
+	return nil
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <terms>",
+	Short: "full-text search stored product titles, brands, descriptions and ingredients",
+	Long: `Search stored products, ranking matches in the title above the brand, description and ingredients.
+  Terms use the sqlite full-text syntax: words must all match, "quoted phrases" match in order,
+  and OR, NOT and prefix* queries are supported, e.g. tesco search "greek yoghurt"
+  `,
+	PreRunE: requireTerms,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var filter store.Filter
+		if err := searchDiet.apply(&filter); err != nil {
+			return err
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		results, err := store.Search(db, strings.Join(args, " "), filter, searchLimit)
+		if err != nil {
+			return err
+		}
+		rows := make([]output.Row, len(results))
+		for i, result := range results {
+			score := result.Score
+			rows[i] = output.ProductRow(result.Product)
+			rows[i].Score = &score
+			rows[i].Snippet = result.Snippet
+		}
+		return writeOutput(output.Document{Rows: rows, Score: "rank"})
+	},
+}
+
+var getSearchCmd = &cobra.Command{
+	Use:     "search <term>",
+	Short:   "get the products in Tesco's search results for a term",
+	PreRunE: requireTerms,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results, err := search.Get(strings.Join(args, " "), getSearchPages)
+		if err != nil {
+			return err
+		}
+		rows := output.ListingRows(*results, "results.productItems.#.product")
+		return writeOutput(output.Document{JSON: *results, Rows: rows})
+	},
+}
+
+var scrapeSearchCmd = &cobra.Command{
+	Use:     "search <term>",
+	Short:   "scrape the products in Tesco's search results for a term and persist to the database",
+	PreRunE: requireTerms,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		term := strings.Join(args, " ")
+		return category.ScrapeWith(dbPath, func(productResults chan category.ProductResult, db *sql.DB, stats *progress.Stats) error {
+			return search.Scrape(term, scrapeSearchPages, concurrency, productResults, db, stats)
+		})
+	},
+}
+
+func init() {
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "maximum number of results, 0 for all")
+	addDietFlags(searchCmd, &searchDiet)
+	RootCmd.AddCommand(searchCmd)
+
+	getSearchCmd.Flags().IntVar(&getSearchPages, "pages", 1, "number of results pages to fetch, 0 for all")
+	GetCmd.AddCommand(getSearchCmd)
+	scrapeSearchCmd.Flags().IntVar(&scrapeSearchPages, "pages", 0, "number of results pages to scrape, 0 for all")
+	ScrapeCmd.AddCommand(scrapeSearchCmd)
+}
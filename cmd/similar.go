@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mattburman/tesco/internal/store"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/similar"
+	"github.com/spf13/cobra"
+)
+
+var similarScope string
+var similarPrefer string
+var similarPriceWeight float64
+var similarLimit int
+var similarDiet dietFlags
+
+var similarCmd = &cobra.Command{
+	Use:   "similar <id>",
+	Short: "suggest stored products nearest to a product, such as cheaper or higher-protein substitutes",
+	Long: `Suggest the stored products nearest to a product by their nutrients per 100g or 100ml, each
+  scaled by its daily reference intake, whether they share a shelf or aisle, and their price per 100g.
+  --scope limits substitutes to the same shelf, aisle or department, or any. --prefer cheaper or
+  protein counts products dearer or with less protein per 100g as further away, so near products that
+  are cheaper or have more protein rank first. --price-weight 0 ignores price.
+  The distance column is lower for nearer products.
+  e.g. tesco similar 300400483 --prefer cheaper --scope shelf -o table
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scope, err := similar.ParseScope(similarScope)
+		if err != nil {
+			return err
+		}
+		prefer, err := similar.ParsePrefer(similarPrefer)
+		if err != nil {
+			return err
+		}
+		var filter store.Filter
+		if err := similarDiet.apply(&filter); err != nil {
+			return err
+		}
+
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		targets, err := store.Query(db, "f.product_id = ?", []interface{}{args[0]}, "", 1)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("product %v is not stored, scrape it first", args[0])
+		}
+		stored, err := store.Products(db, filter)
+		if err != nil {
+			return err
+		}
+		candidates := make([]*product.Product, len(stored))
+		for i, s := range stored {
+			candidates[i] = s.Product
+		}
+
+		matches, err := similar.Find(targets[0].Product, candidates, similar.Options{
+			Scope:       scope,
+			Prefer:      prefer,
+			PriceWeight: similarPriceWeight,
+			Limit:       similarLimit,
+		})
+		if err != nil {
+			return err
+		}
+		rows := make([]output.Row, len(matches))
+		for i, m := range matches {
+			distance := m.Distance
+			rows[i] = output.ProductRow(m.Product)
+			rows[i].Score = &distance
+		}
+		return writeOutput(output.Document{Rows: rows, Score: "distance"})
+	},
+}
+
+func init() {
+	similarCmd.Flags().StringVar(&similarScope, "scope", string(similar.Aisle), "where substitutes may come from: shelf, aisle, department or any")
+	similarCmd.Flags().StringVar(&similarPrefer, "prefer", "", "count substitutes that are dearer or have less protein as further away: cheaper, protein")
+	similarCmd.Flags().Float64Var(&similarPriceWeight, "price-weight", similar.DefaultPriceWeight, "how much a difference in price counts against a substitute, 0 to ignore price")
+	similarCmd.Flags().IntVar(&similarLimit, "limit", 10, "number of substitutes to show, 0 for all")
+	addDietFlags(similarCmd, &similarDiet)
+	RootCmd.AddCommand(similarCmd)
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mattburman/tesco/internal/category"
+	"github.com/mattburman/tesco/internal/store"
+	pkgcategory "github.com/mattburman/tesco/pkg/category"
+	"github.com/mattburman/tesco/pkg/output"
+	"github.com/mattburman/tesco/pkg/progress"
+	"github.com/mattburman/tesco/pkg/watch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var watchRule watch.Rule
+var alertTarget string
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "manage price and promotion alerts on products",
+	Long: `Watch products to be alerted when they get cheap. Watches are checked against new prices after
+  every scrape and watch refresh, and alert once each time they become met.
+  Alerts go to stdout, or with --alert (or alert in the config file) to a webhook URL, which is
+  posted {"alerts": [...]}, or any other path, which alerts are appended to as JSON lines.
+  `,
+}
+
+var watchAddCmd = &cobra.Command{
+	Use:   "add <id>",
+	Short: "watch a product, e.g. tesco watch add 254918073 --below 1.20 --on-promo",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("No product ID supplied")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		watchRule.ProductID = args[0]
+		rule, err := store.AddWatch(db, watchRule)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("watching %v %v as watch %v\n", rule.ProductID, rule, rule.ID)
+		return nil
+	},
+}
+
+var watchListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list watched products",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		rules, err := store.Watches(db)
+		if err != nil {
+			return err
+		}
+		if outputFormat == output.Table {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tPRODUCT\tALERT WHEN")
+			for _, rule := range rules {
+				fmt.Fprintf(tw, "%v\t%v\t%v\n", rule.ID, rule.ProductID, rule)
+			}
+			return tw.Flush()
+		}
+		raw, err := json.Marshal(rules)
+		if err != nil {
+			return fmt.Errorf("unable to marshal watches: %v", err)
+		}
+		return writeJSONOutput(string(raw))
+	},
+}
+
+var watchRemoveCmd = &cobra.Command{
+	Use:   "remove <watch id>",
+	Short: "stop watching, by the ID shown by watch list",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("No watch ID supplied")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("watch ID was not an integer: %v", err)
+		}
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return store.RemoveWatch(db, id)
+	},
+}
+
+var watchRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "fetch the latest prices of watched products and send alerts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		rules, err := store.Watches(db)
+		db.Close()
+		if err != nil {
+			return err
+		}
+		ids := []string{}
+		seen := map[string]bool{}
+		for _, rule := range rules {
+			if !seen[rule.ProductID] {
+				seen[rule.ProductID] = true
+				ids = append(ids, rule.ProductID)
+			}
+		}
+		started := time.Now()
+		err = category.ScrapeWith(dbPath, func(productResults chan category.ProductResult, db *sql.DB, stats *progress.Stats) error {
+			return pkgcategory.ScrapeIDs(ids, concurrency, productResults, stats)
+		})
+		if err != nil {
+			return err
+		}
+		if err := reportChanges(started); err != nil {
+			return err
+		}
+		return checkWatches()
+	},
+}
+
+// checkWatches evaluates the watches against newly fetched prices and sends any alerts
+// to --alert, or the alert config setting
+func checkWatches() error {
+	db, err := store.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	alerts, ack, err := store.CheckWatches(db)
+	if err != nil {
+		return err
+	}
+	if len(alerts) > 0 {
+		target := alertTarget
+		if target == "" {
+			target = viper.GetString("alert")
+		}
+		// watches are only marked as fired once their alerts are sent
+		if err := watch.NewNotifier(target, os.Stdout).Notify(alerts); err != nil {
+			return err
+		}
+	}
+	return ack()
+}
+
+func init() {
+	watchAddCmd.Flags().Float64Var(&watchRule.Below, "below", 0, "alert when the price of one item, with any offer, is below this")
+	watchAddCmd.Flags().BoolVar(&watchRule.OnPromo, "on-promo", false, "alert when the product has an offer running")
+	watchCmd.AddCommand(watchAddCmd)
+	watchCmd.AddCommand(watchListCmd)
+	watchCmd.AddCommand(watchRemoveCmd)
+	watchRefreshCmd.Flags().IntVar(&concurrency, "concurrency", 3, "number of simultaneous requests")
+	watchRefreshCmd.Flags().StringVar(&alertTarget, "alert", "", "send alerts to a webhook URL or append them to a file, rather than stdout")
+	watchCmd.AddCommand(watchRefreshCmd)
+	RootCmd.AddCommand(watchCmd)
+}
@@ -4,62 +4,69 @@ package category
 import (
 	"database/sql"
 	"fmt"
+	"github.com/mattburman/tesco/internal/store"
 	"github.com/mattburman/tesco/pkg/category"
+	"github.com/mattburman/tesco/pkg/metrics"
+	"github.com/mattburman/tesco/pkg/progress"
+	"os"
+	"sync"
+	"time"
 )
 
 var Get = category.Get
 
-// ScrapeToSqlite scrapes the products in a category URL to sqlite3
-func ScrapeToSqlite(url string, concurrency int) error {
-	// set up db
-	db, err := sql.Open("sqlite3", "./data.db")
+// ProductResult is a scraped product payload
+type ProductResult = category.ProductResult
+
+// ScrapeToSqlite scrapes the products in a category URL to the sqlite3 database at dbPath
+func ScrapeToSqlite(dbPath string, url string, concurrency int) error {
+	return ScrapeWith(dbPath, func(productResults chan ProductResult, db *sql.DB, stats *progress.Stats) error {
+		return category.Scrape(url, concurrency, productResults, db, stats)
+	})
+}
+
+// Scraper places scraped products on productResults, closing it when done
+type Scraper func(productResults chan ProductResult, db *sql.DB, stats *progress.Stats) error
+
+// ScrapeWith runs scrape against the sqlite3 database at dbPath, saving the products it
+// finds and reporting progress on stdout
+func ScrapeWith(dbPath string, scrape Scraper) error {
+	db, err := store.Open(dbPath)
 	if err != nil {
-		return fmt.Errorf("failed to open db: %v", err)
+		return err
 	}
 	defer db.Close()
-	err = db.Ping()
-	if err != nil {
-		return fmt.Errorf("unable to connect to db: %v", err)
-	}
-	insertProduct, err := db.Prepare("INSERT INTO products(id, source, raw) VALUES(?, 'product', ?)")
-	if err != nil {
-		return fmt.Errorf("failed to create prepared statement for productResults: %v", err)
-	}
+	stats := progress.New()
+	reporter := progress.NewReporter(stats, os.Stdout, progress.IsTerminal(os.Stdout))
+	reporter.Start()
+	defer reporter.Stop()
 
 	// the channel we will receive products on
-	productResults := make(chan category.ProductResult)
+	productResults := make(chan ProductResult)
 
 	// start some insertion workers that take insertion jobs from the channel
+	var workers sync.WaitGroup
 	for i := 1; i <= 8; i++ {
+		workers.Add(1)
 		go func() {
+			defer workers.Done()
 			for reqResult := range productResults {
-				fmt.Println(reqResult.Id)
-				res, err := insertProduct.Exec(reqResult.Id, reqResult.Json)
-				if err != nil {
-					fmt.Printf("failed to execute query for %v: %v\n", reqResult.Id, err)
-					continue
-				}
-				rowCnt, err := res.RowsAffected()
-				if err != nil {
-					fmt.Printf("failed to get RowsAffected for attempted insertion of %v: %v\n", reqResult.Id, err)
-					continue
-				}
-				if rowCnt == 0 {
-					fmt.Printf("no insertion for %v", reqResult.Id)
-					continue
-				}
-				lastID, err := res.LastInsertId()
+				metrics.QueueDepth.WithLabelValues("productResults").Dec()
+				start := time.Now()
+				err := store.Save(db, store.Record{ID: reqResult.Id, URL: reqResult.Url, Raw: reqResult.Json, FetchedAt: start})
+				metrics.InsertLatency.Observe(time.Since(start).Seconds())
 				if err != nil {
-					fmt.Printf("failed to get LastInsertId for attempted insertion of %v: %v\n", reqResult.Id, err)
+					fmt.Fprintf(os.Stderr, "failed to save %v: %v\n", reqResult.Id, err)
 					continue
 				}
-				fmt.Printf("inserted product: %v\n", lastID)
+				stats.AddInserted()
 			}
 		}()
 	}
 
-	// scrape the category to place products on the productResults channel
-	err = category.Scrape(url, concurrency, productResults, db)
+	// scrape to place products on the productResults channel
+	err = scrape(productResults, db, stats)
+	workers.Wait()
 	if err != nil {
 		return fmt.Errorf("failed to scrape productResults: %v", err)
 	}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/basket"
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+// BasketSummary is a named basket and how much is in it
+type BasketSummary struct {
+	Name      string    `json:"name"`
+	Products  int       `json:"products"`
+	Packs     int       `json:"packs"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AddToBasket adds quantity packs of a stored product to the named basket, creating the
+// basket if needed, and returns the number of packs of it now in the basket
+func AddToBasket(db *sql.DB, name, productID string, quantity int) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("a basket needs a name")
+	}
+	if quantity <= 0 {
+		return 0, fmt.Errorf("quantity %v must be at least 1", quantity)
+	}
+	var stored int
+	if err := db.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND source = 'product'", productID).Scan(&stored); err != nil {
+		return 0, fmt.Errorf("failed to look up product %v: %v", productID, err)
+	}
+	if stored == 0 {
+		return 0, fmt.Errorf("product %v is not stored, scrape it first", productID)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().Unix()
+	_, err = tx.Exec("INSERT OR IGNORE INTO baskets(name, created_at) VALUES(?, ?)", name, now)
+	if err == nil {
+		_, err = tx.Exec("INSERT OR IGNORE INTO basket_items(basket, product_id, quantity, added_at) VALUES(?, ?, 0, ?)", name, productID, now)
+	}
+	if err == nil {
+		_, err = tx.Exec("UPDATE basket_items SET quantity = quantity + ? WHERE basket = ? AND product_id = ?", quantity, name, productID)
+	}
+	var total int
+	if err == nil {
+		err = tx.QueryRow("SELECT quantity FROM basket_items WHERE basket = ? AND product_id = ?", name, productID).Scan(&total)
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to add %v to basket %v: %v", productID, name, err)
+	}
+	return total, tx.Commit()
+}
+
+// RemoveFromBasket takes quantity packs of a product out of the named basket, or all of
+// them when quantity is 0 or less, returning an error if it is not in the basket
+func RemoveFromBasket(db *sql.DB, name, productID string, quantity int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	var result sql.Result
+	if quantity > 0 {
+		result, err = tx.Exec("UPDATE basket_items SET quantity = quantity - ? WHERE basket = ? AND product_id = ?", quantity, name, productID)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM basket_items WHERE basket = ? AND product_id = ? AND quantity <= 0", name, productID)
+		}
+	} else {
+		result, err = tx.Exec("DELETE FROM basket_items WHERE basket = ? AND product_id = ?", name, productID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to remove %v from basket %v: %v", productID, name, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		tx.Rollback()
+		return fmt.Errorf("product %v is not in basket %v", productID, name)
+	}
+	return tx.Commit()
+}
+
+// DeleteBasket deletes the named basket and everything in it
+func DeleteBasket(db *sql.DB, name string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM baskets WHERE name = ?", name)
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM basket_items WHERE basket = ?", name)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete basket %v: %v", name, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		tx.Rollback()
+		return fmt.Errorf("no basket named %v", name)
+	}
+	return tx.Commit()
+}
+
+// Baskets returns every basket by name
+func Baskets(db *sql.DB) ([]BasketSummary, error) {
+	rows, err := db.Query(`SELECT b.name, b.created_at, COUNT(i.product_id), COALESCE(SUM(i.quantity), 0)
+		FROM baskets b LEFT JOIN basket_items i ON i.basket = b.name
+		GROUP BY b.name ORDER BY b.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baskets: %v", err)
+	}
+	defer rows.Close()
+	baskets := []BasketSummary{}
+	for rows.Next() {
+		var b BasketSummary
+		var createdAt int64
+		if err := rows.Scan(&b.Name, &createdAt, &b.Products, &b.Packs); err != nil {
+			return nil, fmt.Errorf("failed to scan basket: %v", err)
+		}
+		b.CreatedAt = time.Unix(createdAt, 0).UTC()
+		baskets = append(baskets, b)
+	}
+	return baskets, rows.Err()
+}
+
+// BasketItems returns the products in the named basket in the order they were added,
+// returning an error if there is no such basket
+func BasketItems(db *sql.DB, name string) ([]basket.Item, error) {
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM baskets WHERE name = ?", name).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up basket %v: %v", name, err)
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("no basket named %v", name)
+	}
+	rows, err := db.Query(`SELECT p.id, p.raw, i.quantity FROM basket_items i
+		JOIN products p ON p.id = i.product_id AND p.source = 'product'
+		WHERE i.basket = ? ORDER BY i.added_at, i.product_id`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket %v: %v", name, err)
+	}
+	defer rows.Close()
+	items := []basket.Item{}
+	for rows.Next() {
+		var id, raw string
+		var item basket.Item
+		if err := rows.Scan(&id, &raw, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan basket item: %v", err)
+		}
+		if item.Product, err = product.FromResources(raw, product.IDToURL(id)); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to parse stored product %v: %v\n", id, err)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
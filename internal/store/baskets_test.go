@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRemoveFromBasket(t *testing.T) {
+	db := openTest(t)
+	if err := Save(db, Record{ID: "300400483", Raw: steak, FetchedAt: time.Unix(1700000000, 0)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := AddToBasket(db, "week", "300400483", 3); err != nil {
+		t.Fatalf("AddToBasket() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		quantity  int
+		wantErr   bool
+		wantPacks int
+	}{
+		{"some packs", 2, false, 1},
+		{"the rest", 5, false, 0},
+		{"not in the basket", 1, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RemoveFromBasket(db, "week", "300400483", tt.quantity); (err != nil) != tt.wantErr {
+				t.Fatalf("RemoveFromBasket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n := count(t, db, "SELECT COALESCE(SUM(quantity), 0) FROM basket_items WHERE basket = 'week'"); n != tt.wantPacks {
+				t.Errorf("RemoveFromBasket() left %v packs, want %v", n, tt.wantPacks)
+			}
+		})
+	}
+
+	if err := DeleteBasket(db, "week"); err != nil {
+		t.Fatalf("DeleteBasket() error = %v", err)
+	}
+	if err := DeleteBasket(db, "week"); err == nil {
+		t.Errorf("DeleteBasket() of a deleted basket succeeded, want an error")
+	}
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/tidwall/gjson"
+)
+
+// Record is a raw product payload, the shape of a category.ProductResult plus when it was fetched
+type Record struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Raw       string    `json:"raw"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Reject is a line of an import that could not be loaded
+type Reject struct {
+	Line int
+	ID   string
+	Err  error
+}
+
+func (r Reject) Error() string {
+	return fmt.Sprintf("line %v (%v): %v", r.Line, r.ID, r.Err)
+}
+
+// upsert inserts r, replacing any stored payload for the same product unless it was fetched
+// later. It returns false when the stored payload is newer, or r has no fetch time and the
+// stored one has.
+func upsert(db execer, r Record) (bool, error) {
+	var fetchedAt interface{}
+	if !r.FetchedAt.IsZero() {
+		fetchedAt = r.FetchedAt.Unix()
+	}
+	result, err := db.Exec(`INSERT INTO products(id, source, raw, fetched_at) VALUES(?, 'product', ?, ?)
+		ON CONFLICT(id, source) DO UPDATE SET raw=excluded.raw, fetched_at=excluded.fetched_at
+		WHERE products.fetched_at IS NULL OR excluded.fetched_at >= products.fetched_at`,
+		r.ID, r.Raw, fetchedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert %v: %v", r.ID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert %v: %v", r.ID, err)
+	}
+	return n > 0, nil
+}
+
+// Validate checks that r holds a parseable product and returns it with the raw payload
+// reduced to the resources json and the ID and URL filled in from each other if missing.
+// The raw payload may be the resources json stored by a scrape or a product page's HTML.
+func Validate(r Record) (Record, error) {
+	if r.ID == "" && r.URL == "" {
+		return r, errors.New("record has neither id nor url")
+	}
+	if r.URL == "" {
+		r.URL = product.IDToURL(r.ID)
+	}
+	id, err := product.URLToID(r.URL)
+	if err != nil {
+		return r, err
+	}
+	if r.ID == "" {
+		r.ID = id
+	}
+	if r.ID != id {
+		return r, fmt.Errorf("id %v does not match url %v", r.ID, r.URL)
+	}
+
+	if !gjson.Valid(r.Raw) {
+		resources, err := product.ExtractResources(r.Raw)
+		if err != nil {
+			return r, fmt.Errorf("raw is neither json nor a product page: %v", err)
+		}
+		r.Raw = *resources
+	}
+	p, err := product.FromResources(r.Raw, r.URL)
+	if err != nil {
+		return r, err
+	}
+	if p.Name() == "" {
+		return r, errors.New("product has no title")
+	}
+	return r, nil
+}
+
+// ImportResult counts the records of an import
+type ImportResult struct {
+	Imported int
+	// Older are the records not imported because the stored product was fetched later
+	Older   int
+	Rejects []Reject
+}
+
+// Import reads JSON Lines of Records from rd, validating and upserting each one in a single
+// transaction, so nothing is imported if it fails part way through. Records older than the
+// stored product are skipped, so importing an old dump does not roll back newer fetches.
+func Import(db *sql.DB, rd io.Reader) (ImportResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("failed to begin import: %v", err)
+	}
+	result, err := importRecords(tx, rd)
+	if err != nil {
+		tx.Rollback()
+		return ImportResult{Rejects: result.Rejects}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ImportResult{Rejects: result.Rejects}, fmt.Errorf("failed to commit import: %v", err)
+	}
+	return result, nil
+}
+
+func importRecords(tx *sql.Tx, rd io.Reader) (ImportResult, error) {
+	scanner := bufio.NewScanner(rd)
+	// product payloads are large, allow lines up to 16MB
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+
+	result := ImportResult{Rejects: []Reject{}}
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			result.Rejects = append(result.Rejects, Reject{Line: line, Err: fmt.Errorf("invalid json: %v", err)})
+			continue
+		}
+		r, err := Validate(r)
+		if err != nil {
+			result.Rejects = append(result.Rejects, Reject{Line: line, ID: r.ID, Err: err})
+			continue
+		}
+		stored, err := save(tx, r)
+		if err != nil {
+			return result, err
+		}
+		if stored {
+			result.Imported++
+		} else {
+			result.Older++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read line %v: %v", line+1, err)
+	}
+	return result, nil
+}
+
+// Dump writes the stored products matching filter as JSON Lines of Records, the format read
+// by Import. Rows that fail to parse are kept unless filter has to parse them to match.
+func Dump(db *sql.DB, w io.Writer, filter Filter) error {
+	query := "SELECT id, raw, fetched_at FROM products WHERE source='product'"
+	args := []interface{}{}
+	if !filter.UpdatedSince.IsZero() {
+		query += " AND fetched_at >= ?"
+		args = append(args, filter.UpdatedSince.Unix())
+	}
+	rows, err := db.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return fmt.Errorf("failed to get products from DB: %v", err)
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	for rows.Next() {
+		var r Record
+		var fetchedAt sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.Raw, &fetchedAt); err != nil {
+			return fmt.Errorf("failed to scan product: %v", err)
+		}
+		r.URL = product.IDToURL(r.ID)
+		if filter.byProduct() {
+			p, err := product.FromResources(r.Raw, r.URL)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to parse stored product %v: %v\n", r.ID, err)
+				continue
+			}
+			if !filter.Match(p) {
+				continue
+			}
+		}
+		if fetchedAt.Valid {
+			r.FetchedAt = time.Unix(fetchedAt.Int64, 0).UTC()
+		}
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("unable to encode %v: %v", r.ID, err)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	db := openTest(t)
+	records := []Record{
+		{ID: "300400483", Raw: steak, FetchedAt: time.Unix(1700000000, 0)},
+		{ID: "300400484", Raw: strings.Replace(steak, `"TESCO"`, `"HAWKSTONE"`, 1), FetchedAt: time.Unix(1800000000, 0)},
+		{ID: "300400485", Raw: `{"productDetails":{}}`, FetchedAt: time.Unix(1800000000, 0)},
+	}
+	for _, r := range records {
+		if err := Save(db, r); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"everything", Filter{}, []string{"300400483", "300400484", "300400485"}},
+		{"brand", Filter{Brands: []string{"tesco"}}, []string{"300400483"}},
+		{"updated since", Filter{UpdatedSince: time.Unix(1750000000, 0)}, []string{"300400484", "300400485"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Dump(db, &buf, tt.filter); err != nil {
+				t.Fatalf("Dump() error = %v", err)
+			}
+			ids := []string{}
+			dec := json.NewDecoder(&buf)
+			for dec.More() {
+				var r Record
+				if err := dec.Decode(&r); err != nil {
+					t.Fatalf("failed to decode dump: %v", err)
+				}
+				ids = append(ids, r.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Dump() ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	db := openTest(t)
+	line := func(id string, fetchedAt int64, price string) string {
+		raw := strings.Replace(steak, `"price":3.55`, `"price":`+price, 1)
+		b, err := json.Marshal(Record{ID: id, Raw: raw, FetchedAt: time.Unix(fetchedAt, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b) + "\n"
+	}
+	tests := []struct {
+		name         string
+		input        string
+		wantImported int
+		wantOlder    int
+		wantRejects  int
+		wantErr      bool
+		wantStored   int
+		wantPrice    float64
+	}{
+		{"rejects invalid lines", line("300400483", 1700000000, "3.55") + "{not json}\n", 1, 0, 1, false, 1, 3.55},
+		// a line too long to scan fails the import after the first record, which is rolled back
+		{"rolls back on failure", line("300400484", 1700000000, "3.55") + strings.Repeat("x", 17*1024*1024), 0, 0, 0, true, 1, 3.55},
+		{"newer fetch", line("300400483", 1800000000, "3.95"), 1, 0, 0, false, 1, 3.95},
+		// importing an old dump leaves the newer fetch and its facts in place
+		{"older fetch", line("300400483", 1700000000, "3.55"), 0, 1, 0, false, 1, 3.95},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Import(db, strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Import() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Imported != tt.wantImported || result.Older != tt.wantOlder || len(result.Rejects) != tt.wantRejects {
+				t.Errorf("Import() = %v imported, %v older, %v rejects, want %v, %v, %v",
+					result.Imported, result.Older, len(result.Rejects), tt.wantImported, tt.wantOlder, tt.wantRejects)
+			}
+			if n := count(t, db, "SELECT COUNT(*) FROM products"); n != tt.wantStored {
+				t.Errorf("Import() left %v products stored, want %v", n, tt.wantStored)
+			}
+			var price float64
+			if err := db.QueryRow("SELECT price FROM product_facts WHERE product_id = '300400483'").Scan(&price); err != nil {
+				t.Fatalf("failed to read facts: %v", err)
+			}
+			if price != tt.wantPrice {
+				t.Errorf("Import() left price %v, want %v", price, tt.wantPrice)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/allergens"
+	"github.com/mattburman/tesco/pkg/gtin"
+	"github.com/mattburman/tesco/pkg/ingredients"
+	"github.com/mattburman/tesco/pkg/nutrition"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/rank"
+	"github.com/mattburman/tesco/pkg/review"
+)
+
+// indexVersion is bumped whenever the facts or tags derived from a product change,
+// so that Reindex recomputes them for every stored product
+const indexVersion = 15
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Save upserts r and indexes it for querying in a single transaction. The raw payload is
+// kept even if indexing fails, which is reported on stderr like Reindex does.
+func Save(db *sql.DB, r Record) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := save(tx, r); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// save upserts and indexes r within tx, skipping the index if it fails. It returns false
+// without indexing when a later fetch of the product is already stored.
+func save(tx *sql.Tx, r Record) (bool, error) {
+	stored, err := upsert(tx, r)
+	if err != nil || !stored {
+		return false, err
+	}
+	_, err = indexOrSkip(tx, r)
+	return err == nil, err
+}
+
+// indexOrSkip indexes r within a savepoint of tx. A product that fails to index is reported
+// on stderr and its partial index rolled back, leaving the rest of tx intact.
+func indexOrSkip(tx *sql.Tx, r Record) (bool, error) {
+	if _, err := tx.Exec("SAVEPOINT index_product"); err != nil {
+		return false, fmt.Errorf("failed to begin indexing %v: %v", r.ID, err)
+	}
+	if err := index(tx, r); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if _, err := tx.Exec("ROLLBACK TO index_product"); err != nil {
+			return false, fmt.Errorf("failed to roll back indexing %v: %v", r.ID, err)
+		}
+		_, err := tx.Exec("RELEASE index_product")
+		return false, err
+	}
+	_, err := tx.Exec("RELEASE index_product")
+	return err == nil, err
+}
+
+// index derives the queryable facts and tags for a stored product
+func index(db execer, r Record) error {
+	p, err := product.FromResources(r.Raw, product.IDToURL(r.ID))
+	if err != nil {
+		return fmt.Errorf("failed to parse %v for indexing: %v", r.ID, err)
+	}
+
+	var fetchedAt interface{}
+	if !r.FetchedAt.IsZero() {
+		fetchedAt = r.FetchedAt.Unix()
+	}
+	var pricePer100 interface{}
+	if price, ok := rank.PricePer100(p.UnitPrice(), p.UnitOfMeasure()); ok {
+		pricePer100 = price
+	}
+	var barcode interface{}
+	if normalized, err := gtin.Normalize(p.GTIN()); err == nil {
+		barcode = normalized
+	}
+	ingredientsJSON, err := json.Marshal(p.Ingredients())
+	if err != nil {
+		return fmt.Errorf("failed to marshal ingredients of %v: %v", r.ID, err)
+	}
+	// health is left null for products without nutrition per 100g or 100ml
+	var nutriScore, nutriGrade, fatLight, saturatesLight, sugarsLight, saltLight interface{}
+	if health, ok := nutrition.Assess(p); ok {
+		if health.NutriScore != nil {
+			nutriScore, nutriGrade = *health.NutriScore, health.Grade
+		}
+		fatLight, saturatesLight = nullLight(health.Per100.Fat), nullLight(health.Per100.Saturates)
+		sugarsLight, saltLight = nullLight(health.Per100.Sugars), nullLight(health.Per100.Salt)
+	}
+	// pack totals are left null when the pack size or servings are unknown
+	var packSize, packUnit, servings, packKcal, packProtein, servingKcal, servingProtein, costPerServing interface{}
+	if pack := p.Pack(); pack.Size() > 0 {
+		packSize, packUnit = pack.Size(), pack.Unit()
+	}
+	if n := p.Servings(); n > 0 {
+		servings = n
+	}
+	if perPack, ok := p.PerPack(); ok {
+		packKcal, packProtein = perPack.Kcal(), perPack.Protein()
+	}
+	if serving, ok := p.Serving(); ok {
+		servingKcal, servingProtein = serving.Kcal(), serving.Protein()
+	}
+	if cost, ok := p.CostPerServing(); ok {
+		costPerServing = cost
+	}
+	// the rating summary covers every review, otherwise it is averaged from the stored reviews
+	// below, which are kept across fetches and so may be more than the latest page
+	var rating, reviewCount interface{}
+	reviews, stats, hasStats := review.FromProduct(p)
+	if hasStats {
+		rating, reviewCount = stats.Rating, stats.Count
+	}
+	// nutrients are stored per 100g or 100ml, the basis rank.Vars compares products on,
+	// and left null when the product does not give them
+	per100, ok := p.PerComp().Scale(100)
+	if !ok {
+		per100 = p.PerComp()
+	}
+	nutrient := func(n product.Nutrient, value float64) interface{} {
+		if !per100.Has(n) {
+			return nil
+		}
+		return value
+	}
+	categories := p.Categories()
+	_, err = db.Exec(`INSERT OR REPLACE INTO product_facts(
+		product_id, version, fetched_at, name, brand, super_department, department, aisle, shelf,
+		price, unit_price, unit_of_measure, price_per_100, kcal, protein, carbs, fat, gtin, ingredients,
+		saturates, sugars, fibre, salt, nutri_score, nutri_grade, fat_light, saturates_light, sugars_light, salt_light,
+		pack_size, pack_unit, servings, pack_kcal, pack_protein, serving_kcal, serving_protein, cost_per_serving,
+		rating, review_count
+	) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, indexVersion, fetchedAt, p.Name(), p.Brand(), categories[0], categories[1], categories[2], categories[3],
+		p.Price(), p.UnitPrice(), p.UnitOfMeasure(), pricePer100,
+		nutrient(product.Energy, per100.Kcal()), nutrient(product.Protein, per100.Protein()),
+		nutrient(product.Carbs, per100.Carbs()), nutrient(product.Fat, per100.Fat()),
+		barcode, string(ingredientsJSON),
+		nutrient(product.Saturates, per100.Saturates()), nutrient(product.Sugars, per100.Sugars()),
+		nutrient(product.Fibre, per100.Fibre()), nutrient(product.Salt, per100.Salt()),
+		nutriScore, nutriGrade, fatLight, saturatesLight, sugarsLight, saltLight,
+		packSize, packUnit, servings, packKcal, packProtein, servingKcal, servingProtein, costPerServing,
+		rating, reviewCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to index facts for %v: %v", r.ID, err)
+	}
+
+	if _, err := db.Exec("DELETE FROM product_tags WHERE product_id = ?", r.ID); err != nil {
+		return fmt.Errorf("failed to clear tags for %v: %v", r.ID, err)
+	}
+	for _, tag := range Tags(p) {
+		if _, err := db.Exec("INSERT OR IGNORE INTO product_tags(product_id, tag) VALUES(?, ?)", r.ID, tag); err != nil {
+			return fmt.Errorf("failed to index tag %v for %v: %v", tag, r.ID, err)
+		}
+	}
+	if err := indexPromotions(db, r, p); err != nil {
+		return err
+	}
+	if err := indexReviews(db, r, reviews); err != nil {
+		return err
+	}
+	if !hasStats {
+		if err := averageReviews(db, r.ID); err != nil {
+			return err
+		}
+	}
+	if err := recordSnapshot(db, r, p); err != nil {
+		return err
+	}
+	return indexSearch(db, p)
+}
+
+// nullLight returns a traffic light for storing, null when it is unknown
+func nullLight(light nutrition.Light) interface{} {
+	if light == "" {
+		return nil
+	}
+	return string(light)
+}
+
+// Tags returns the lower case kind:value tags a product can be queried by, e.g. allergen:milk
+func Tags(p *product.Product) []string {
+	tags := []string{}
+	add := func(kind, value string) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" {
+			tags = append(tags, kind+":"+value)
+		}
+	}
+	add("brand", p.Brand())
+	add("department", p.Department())
+	add("aisle", p.Aisle())
+	add("shelf", p.Shelf())
+	for _, allergen := range ingredients.Allergens(p.Ingredients()) {
+		add("allergen", allergen)
+	}
+	// the 14 regulated allergens are tagged by status, e.g. contains:milk or free-from:gluten,
+	// and those contained or possibly contained as allergen:milk too
+	info := allergens.FromProduct(p)
+	for _, allergen := range allergens.All {
+		status := info.Status(allergen)
+		if status == allergens.Unknown {
+			continue
+		}
+		add(status.String(), string(allergen))
+		if status == allergens.Contains || status == allergens.MayContain {
+			add("allergen", string(allergen))
+		}
+	}
+	for _, diet := range info.Diets {
+		add("diet", string(diet))
+	}
+	return dedupe(tags)
+}
+
+func dedupe(tags []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+// Reindex indexes stored products that have not been indexed since they were last
+// fetched, or were indexed by an older version. It returns the number reindexed.
+func Reindex(db *sql.DB) (int, error) {
+	rows, err := db.Query(`SELECT p.id, p.raw, p.fetched_at FROM products p
+		LEFT JOIN product_facts f ON f.product_id = p.id
+		WHERE p.source = 'product' AND (f.product_id IS NULL OR f.version < ? OR f.fetched_at IS NOT p.fetched_at)`,
+		indexVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find products to index: %v", err)
+	}
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		var fetchedAt sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.Raw, &fetchedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan product: %v", err)
+		}
+		if fetchedAt.Valid {
+			r.FetchedAt = time.Unix(fetchedAt.Int64, 0)
+		}
+		records = append(records, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	indexed := 0
+	for _, r := range records {
+		ok, err := indexOrSkip(tx, r)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if ok {
+			indexed++
+		}
+	}
+	return indexed, tx.Commit()
+}
+
+// Query returns the stored products matching an SQL condition over product_facts, aliased
+// as f, such as one compiled by the query package. orderBy is an optional ORDER BY clause.
+// A limit of 0 or less returns every match.
+func Query(db *sql.DB, where string, args []interface{}, orderBy string, limit int) ([]Stored, error) {
+	if _, err := Reindex(db); err != nil {
+		return nil, err
+	}
+	if where == "" {
+		where = "1"
+	}
+	if orderBy == "" {
+		orderBy = "ORDER BY f.product_id"
+	}
+	query := fmt.Sprintf(`SELECT p.id, p.raw, p.fetched_at FROM product_facts f
+		JOIN products p ON p.id = f.product_id AND p.source = 'product'
+		WHERE %v %v`, where, orderBy)
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %v", err)
+	}
+	defer rows.Close()
+	return scanStored(rows)
+}
+
+// ByGTIN returns the stored products with a GTIN as returned by gtin.Normalize
+func ByGTIN(db *sql.DB, normalized string) ([]Stored, error) {
+	return Query(db, "f.gtin = ?", []interface{}{normalized}, "", 0)
+}
+
+// scanStored parses rows of id, raw and fetched_at. Rows that fail to parse are
+// reported on stderr and skipped.
+func scanStored(rows *sql.Rows) ([]Stored, error) {
+	products := []Stored{}
+	for rows.Next() {
+		var id, raw string
+		var fetchedAt sql.NullInt64
+		if err := rows.Scan(&id, &raw, &fetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %v", err)
+		}
+		p, err := product.FromResources(raw, product.IDToURL(id))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to parse stored product %v: %v\n", id, err)
+			continue
+		}
+		stored := Stored{Product: p}
+		if fetchedAt.Valid {
+			stored.FetchedAt = time.Unix(fetchedAt.Int64, 0).UTC()
+		}
+		products = append(products, stored)
+	}
+	return products, rows.Err()
+}
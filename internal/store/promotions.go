@@ -0,0 +1,175 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/promotion"
+)
+
+// indexPromotions records the offers on a stored product. Offers are kept after they are
+// withdrawn, with first_seen and last_seen the fetch times they were first and last seen at.
+func indexPromotions(db execer, r Record, p *product.Product) error {
+	seen := r.FetchedAt
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+	for _, offer := range promotion.FromProduct(p) {
+		if offer.ID == "" {
+			continue
+		}
+		var effectivePrice, effectiveUnitPrice interface{}
+		if price, ok := offer.EffectivePrice(p.Price()); ok {
+			effectivePrice = price
+		}
+		if price, ok := offer.EffectiveUnitPrice(p.Price(), p.UnitPrice()); ok {
+			effectiveUnitPrice = price
+		}
+		_, err := db.Exec(`INSERT OR IGNORE INTO promotions(product_id, promotion_id, first_seen, last_seen) VALUES(?, ?, ?, ?)`,
+			r.ID, offer.ID, seen.Unix(), seen.Unix())
+		if err == nil {
+			_, err = db.Exec(`UPDATE promotions SET promotion_type = ?, offer_text = ?, start_date = ?, end_date = ?,
+				shelf_price = ?, unit_price = ?, unit_of_measure = ?, effective_price = ?, effective_unit_price = ?,
+				first_seen = MIN(first_seen, ?), last_seen = MAX(last_seen, ?)
+				WHERE product_id = ? AND promotion_id = ?`,
+				offer.Type, offer.Text, unixOrNull(offer.Start), unixOrNull(offer.End),
+				p.Price(), p.UnitPrice(), p.UnitOfMeasure(), effectivePrice, effectiveUnitPrice,
+				seen.Unix(), seen.Unix(), r.ID, offer.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to index promotion %v of %v: %v", offer.ID, r.ID, err)
+		}
+	}
+	return nil
+}
+
+func unixOrNull(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Unix()
+}
+
+// Deal is an offer on a stored product, with the prices it was seen at
+type Deal struct {
+	Stored
+	Offer     promotion.Offer
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// ShelfPrice and UnitPrice are the prices when the offer was last seen
+	ShelfPrice float64
+	UnitPrice  float64
+	// EffectivePrice and EffectiveUnitPrice are nil when the offer is not understood
+	EffectivePrice     *float64
+	EffectiveUnitPrice *float64
+}
+
+// Saving returns the fraction of the shelf price saved by the offer, or 0 if unknown
+func (d Deal) Saving() float64 {
+	if d.EffectivePrice == nil || d.ShelfPrice <= 0 {
+		return 0
+	}
+	return 1 - *d.EffectivePrice/d.ShelfPrice
+}
+
+// Deals returns the offers on stored products matching filter, biggest saving first. When
+// at is set only offers running at that time and still on the latest fetch of the product
+// are returned, otherwise every offer ever seen is.
+func Deals(db *sql.DB, filter Filter, at time.Time, kinds []promotion.Kind) ([]Deal, error) {
+	if _, err := Reindex(db); err != nil {
+		return nil, err
+	}
+	query := `SELECT p.id, p.raw, p.fetched_at, d.promotion_id, d.promotion_type, d.offer_text, d.start_date, d.end_date,
+		d.shelf_price, d.unit_price, d.effective_price, d.effective_unit_price, d.first_seen, d.last_seen
+		FROM promotions d JOIN products p ON p.id = d.product_id AND p.source = 'product'
+		WHERE 1`
+	args := []interface{}{}
+	if !at.IsZero() {
+		query += ` AND (d.start_date IS NULL OR d.start_date <= ?) AND (d.end_date IS NULL OR d.end_date >= ?)
+			AND d.last_seen >= COALESCE(p.fetched_at, 0)`
+		args = append(args, at.Unix(), at.Unix())
+	}
+	if !filter.UpdatedSince.IsZero() {
+		query += " AND p.fetched_at >= ?"
+		args = append(args, filter.UpdatedSince.Unix())
+	}
+	rows, err := db.Query(query+" ORDER BY p.id, d.first_seen", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %v", err)
+	}
+	defer rows.Close()
+
+	// products with several offers are parsed once
+	parsed := map[string]*product.Product{}
+	deals := []Deal{}
+	for rows.Next() {
+		var id, raw, promotionID string
+		var promotionType, text sql.NullString
+		var fetchedAt, start, end sql.NullInt64
+		var shelfPrice, unitPrice, effectivePrice, effectiveUnitPrice sql.NullFloat64
+		var firstSeen, lastSeen int64
+		if err := rows.Scan(&id, &raw, &fetchedAt, &promotionID, &promotionType, &text, &start, &end,
+			&shelfPrice, &unitPrice, &effectivePrice, &effectiveUnitPrice, &firstSeen, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %v", err)
+		}
+		p, ok := parsed[id]
+		if !ok {
+			if p, err = product.FromResources(raw, product.IDToURL(id)); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to parse stored product %v: %v\n", id, err)
+			}
+			parsed[id] = p
+		}
+		if p == nil || !filter.Match(p) {
+			continue
+		}
+
+		offer := promotion.New(promotionID, promotionType.String, "", "", text.String)
+		if start.Valid {
+			offer.Start = time.Unix(start.Int64, 0).UTC()
+		}
+		if end.Valid {
+			offer.End = time.Unix(end.Int64, 0).UTC()
+		}
+		if len(kinds) > 0 && !hasKind(kinds, offer) {
+			continue
+		}
+		deal := Deal{
+			Stored:     Stored{Product: p},
+			Offer:      offer,
+			FirstSeen:  time.Unix(firstSeen, 0).UTC(),
+			LastSeen:   time.Unix(lastSeen, 0).UTC(),
+			ShelfPrice: shelfPrice.Float64,
+			UnitPrice:  unitPrice.Float64,
+		}
+		if fetchedAt.Valid {
+			deal.FetchedAt = time.Unix(fetchedAt.Int64, 0).UTC()
+		}
+		if effectivePrice.Valid {
+			deal.EffectivePrice = &effectivePrice.Float64
+		}
+		if effectiveUnitPrice.Valid {
+			deal.EffectiveUnitPrice = &effectiveUnitPrice.Float64
+		}
+		deals = append(deals, deal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(deals, func(i, j int) bool { return deals[i].Saving() > deals[j].Saving() })
+	return deals, nil
+}
+
+// hasKind reports whether offer is one of kinds, counting any Clubcard offer as a
+// promotion.Clubcard one
+func hasKind(kinds []promotion.Kind, offer promotion.Offer) bool {
+	for _, kind := range kinds {
+		if kind == offer.Kind || (kind == promotion.Clubcard && offer.Clubcard) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/review"
+)
+
+// indexReviews records the reviews fetched with a stored product. Only the latest page of
+// reviews is fetched, so reviews are kept across fetches, identified by their review ID.
+func indexReviews(db execer, r Record, reviews []review.Review) error {
+	seen := r.FetchedAt
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+	for _, rv := range reviews {
+		_, err := db.Exec(`INSERT OR IGNORE INTO reviews(product_id, review_id, first_seen) VALUES(?, ?, ?)`,
+			r.ID, rv.ID, seen.Unix())
+		if err == nil {
+			_, err = db.Exec(`UPDATE reviews SET rating = ?, rating_range = ?, summary = ?, text = ?, source = ?,
+				submitted_at = ?, first_seen = MIN(first_seen, ?)
+				WHERE product_id = ? AND review_id = ?`,
+				rv.Rating, rv.Range, rv.Summary, rv.Text, rv.Source, unixOrNull(rv.Submitted), seen.Unix(), r.ID, rv.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to index review %v of %v: %v", rv.ID, r.ID, err)
+		}
+	}
+	return nil
+}
+
+// averageReviews sets the rating of a product without a rating summary to the mean of its
+// stored reviews, each counted once however many fetches it was seen in
+func averageReviews(db execer, id string) error {
+	_, err := db.Exec(`UPDATE product_facts SET
+		rating = (SELECT AVG(rating) FROM reviews WHERE product_id = ?1),
+		review_count = (SELECT NULLIF(COUNT(rating), 0) FROM reviews WHERE product_id = ?1)
+		WHERE product_id = ?1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to average reviews of %v: %v", id, err)
+	}
+	return nil
+}
+
+// Reviews returns every review stored for a product, newest first
+func Reviews(db *sql.DB, id string) ([]review.Review, error) {
+	if _, err := Reindex(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT review_id, rating, rating_range, summary, text, source, submitted_at
+		FROM reviews WHERE product_id = ? ORDER BY submitted_at DESC, first_seen DESC, review_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews: %v", err)
+	}
+	defer rows.Close()
+
+	reviews := []review.Review{}
+	for rows.Next() {
+		var rv review.Review
+		var rating, ratingRange sql.NullFloat64
+		var summary, text, source sql.NullString
+		var submitted sql.NullInt64
+		if err := rows.Scan(&rv.ID, &rating, &ratingRange, &summary, &text, &source, &submitted); err != nil {
+			return nil, fmt.Errorf("failed to scan review: %v", err)
+		}
+		rv.Rating, rv.Range = rating.Float64, ratingRange.Float64
+		rv.Summary, rv.Text, rv.Source = summary.String, text.String, source.String
+		if submitted.Valid {
+			rv.Submitted = time.Unix(submitted.Int64, 0).UTC()
+		}
+		reviews = append(reviews, rv)
+	}
+	return reviews, rows.Err()
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAverageReviews(t *testing.T) {
+	db := openTest(t)
+	// withReviews adds a page of reviews, without a rating summary, to the steak
+	withReviews := func(entries ...string) string {
+		return strings.Replace(steak, `"product":{`, `"product":{"reviews":{"entries":[`+strings.Join(entries, ",")+`]},`, 1)
+	}
+	first := `{"reviewId":"trn:1","rating":{"value":2,"range":5}}`
+	second := `{"reviewId":"trn:2","rating":{"value":5,"range":5}}`
+	tests := []struct {
+		name       string
+		raw        string
+		fetchedAt  int64
+		wantRating float64
+		wantCount  int
+	}{
+		{"first page", withReviews(first), 1700000000, 2, 1},
+		{"refetch counts a review once", withReviews(second, first), 1700100000, 3.5, 2},
+		{"older reviews drop off the page", withReviews(second), 1700200000, 3.5, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Save(db, Record{ID: "300400483", Raw: tt.raw, FetchedAt: time.Unix(tt.fetchedAt, 0)}); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			var rating sql.NullFloat64
+			var count sql.NullInt64
+			err := db.QueryRow("SELECT rating, review_count FROM product_facts WHERE product_id = '300400483'").Scan(&rating, &count)
+			if err != nil {
+				t.Fatalf("failed to read facts: %v", err)
+			}
+			if rating.Float64 != tt.wantRating || int(count.Int64) != tt.wantCount {
+				t.Errorf("rating, review_count = %v, %v, want %v, %v", rating.Float64, count.Int64, tt.wantRating, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/tidwall/gjson"
+)
+
+// searchColumns are the indexed columns of product_search in order, weighted by how
+// much a match in each counts towards a result's score
+var searchColumns = []struct {
+	name   string
+	weight float64
+}{
+	{"title", 4},
+	{"brand", 2},
+	{"description", 1},
+	{"ingredients", 1},
+}
+
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// SearchResult is a product matching a search, with its relevance score and a snippet of the match
+type SearchResult struct {
+	Stored
+	Score   float64
+	Snippet string
+}
+
+// indexSearch replaces the full-text search entry for a product
+func indexSearch(db execer, p *product.Product) error {
+	// product IDs are numeric so are used as the docid
+	if _, err := db.Exec("DELETE FROM product_search WHERE docid = ?", p.ID()); err != nil {
+		return fmt.Errorf("failed to clear search index for %v: %v", p.ID(), err)
+	}
+	ingredients := []string{}
+	for _, line := range gjson.Get(p.Raw(), "product.details.ingredients").Array() {
+		ingredients = append(ingredients, html.UnescapeString(htmlTags.ReplaceAllString(line.String(), "")))
+	}
+	_, err := db.Exec("INSERT INTO product_search(docid, title, brand, description, ingredients) VALUES(?, ?, ?, ?, ?)",
+		p.ID(), p.Name(), p.Brand(), strings.Join(p.Description(), "\n"), strings.Join(ingredients, "\n"))
+	if err != nil {
+		return fmt.Errorf("failed to index %v for search: %v", p.ID(), err)
+	}
+	return nil
+}
+
+// Search returns stored products matching terms and filter, best first. terms uses the sqlite
+// full-text query syntax, so "greek yoghurt" matches both words and "greek OR natural" either.
+// Rows that fail to parse are reported on stderr and skipped. A limit of 0 or less returns
+// every match.
+func Search(db *sql.DB, terms string, filter Filter, limit int) ([]SearchResult, error) {
+	if _, err := Reindex(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT p.id, p.raw, p.fetched_at,
+			snippet(product_search, '[', ']', '...', -1, 12),
+			matchinfo(product_search, 'pcnalx')
+		FROM product_search s
+		JOIN products p ON p.id = CAST(s.docid AS TEXT) AND p.source = 'product'
+		WHERE product_search MATCH ?`, terms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %v", err)
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var id, raw, snippet string
+		var matchinfo []byte
+		var fetchedAt sql.NullInt64
+		if err := rows.Scan(&id, &raw, &fetchedAt, &snippet, &matchinfo); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %v", err)
+		}
+		p, err := product.FromResources(raw, product.IDToURL(id))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to parse stored product %v: %v\n", id, err)
+			continue
+		}
+		result := SearchResult{Stored: Stored{Product: p}, Snippet: snippet, Score: bm25(matchinfo)}
+		if fetchedAt.Valid {
+			result.FetchedAt = time.Unix(fetchedAt.Int64, 0).UTC()
+		}
+		if !filter.Match(p) || (!filter.UpdatedSince.IsZero() && result.FetchedAt.Before(filter.UpdatedSince)) {
+			continue
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search products: %v", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// bm25 scores a match from an FTS4 matchinfo blob in the 'pcnalx' format, summing the
+// Okapi BM25 score of each phrase over the weighted searchColumns. FTS4 has no ranking
+// function of its own, unlike the bm25() built into FTS5.
+func bm25(matchinfo []byte) float64 {
+	const k1, b = 1.2, 0.75
+
+	// matchinfo is an array of unsigned 32 bit integers in native byte order
+	values := make([]float64, len(matchinfo)/4)
+	for i := range values {
+		values[i] = float64(binary.LittleEndian.Uint32(matchinfo[i*4:]))
+	}
+	if len(values) < 3 {
+		return 0
+	}
+	phrases, columns, rows := int(values[0]), int(values[1]), values[2]
+	if len(values) < 3+2*columns+3*phrases*columns {
+		return 0
+	}
+	averageLength := values[3 : 3+columns]
+	length := values[3+columns : 3+2*columns]
+	hits := values[3+2*columns:]
+
+	score := 0.0
+	for phrase := 0; phrase < phrases; phrase++ {
+		for column := 0; column < columns && column < len(searchColumns); column++ {
+			i := 3 * (phrase*columns + column)
+			inRow, rowsWithHits := hits[i], hits[i+2]
+			if inRow == 0 {
+				continue
+			}
+			idf := math.Log(1 + (rows-rowsWithHits+0.5)/(rowsWithHits+0.5))
+			norm := 1 - b
+			if averageLength[column] > 0 {
+				norm += b * length[column] / averageLength[column]
+			}
+			score += searchColumns[column].weight * idf * inRow * (k1 + 1) / (inRow + k1*norm)
+		}
+	}
+	return score
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	db := openTest(t)
+	for _, r := range []Record{
+		{ID: "300400483", Raw: steak, FetchedAt: time.Unix(1700000000, 0)},
+		{ID: "300400484", Raw: strings.Replace(steak, "Rump", "Sirloin", 1), FetchedAt: time.Unix(1700000000, 0)},
+		{ID: "300400485", Raw: strings.Replace(steak, "Rump", "Rump Rump", 1), FetchedAt: time.Unix(1700000000, 0)},
+	} {
+		if err := Save(db, r); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	// a stored row that no longer parses is left in the search index
+	if _, err := db.Exec(`UPDATE products SET raw = '{"productDetails":{}}' WHERE id = '300400485'`); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		terms string
+		want  []string
+	}{
+		{"steak", []string{"300400483", "300400484"}},
+		{"rump", []string{"300400483"}},
+		{"sirloin OR rump", []string{"300400484", "300400483"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.terms, func(t *testing.T) {
+			results, err := Search(db, tt.terms, Filter{}, 0)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			ids := []string{}
+			for _, r := range results {
+				ids = append(ids, r.ID())
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Search() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
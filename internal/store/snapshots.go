@@ -0,0 +1,131 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/diff"
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+// recordSnapshot keeps the raw payload of r if it differs from the last one kept for the
+// product, compared by the hash of its product data
+func recordSnapshot(db execer, r Record, p *product.Product) error {
+	var fetchedAt interface{}
+	if !r.FetchedAt.IsZero() {
+		fetchedAt = r.FetchedAt.Unix()
+	}
+	_, err := db.Exec(`INSERT INTO snapshots(product_id, hash, raw, fetched_at) SELECT ?, ?, ?, ?
+		WHERE COALESCE((SELECT hash FROM snapshots WHERE product_id = ?
+			ORDER BY COALESCE(fetched_at, 0) DESC, id DESC LIMIT 1), '') != ?`,
+		r.ID, p.Hash(), r.Raw, fetchedAt, r.ID, p.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to record snapshot of %v: %v", r.ID, err)
+	}
+	return nil
+}
+
+// ProductChange is the changes between two consecutive snapshots of a product
+type ProductChange struct {
+	ProductID string        `json:"id"`
+	Name      string        `json:"name"`
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Changes   []diff.Change `json:"changes"`
+}
+
+// History returns the changes between each consecutive pair of snapshots of a product,
+// oldest first, leaving out pairs without any field-level changes
+func History(db *sql.DB, productID string) ([]ProductChange, error) {
+	if _, err := Reindex(db); err != nil {
+		return nil, err
+	}
+	return changes(db, "s.product_id = ?", productID)
+}
+
+// Changes returns the changes in snapshots kept since since, each compared to the snapshot
+// of the product before it, leaving out those without any field-level changes
+func Changes(db *sql.DB, since time.Time) ([]ProductChange, error) {
+	if _, err := Reindex(db); err != nil {
+		return nil, err
+	}
+	return changes(db, "s.fetched_at >= ?", since.Unix())
+}
+
+func changes(db *sql.DB, where string, arg interface{}) ([]ProductChange, error) {
+	results := []ProductChange{}
+	err := snapshotPairs(db, where, arg, func(id string, before, after *product.Product, from, to time.Time) {
+		if changes := diff.Products(before, after); len(changes) > 0 {
+			results = append(results, ProductChange{ProductID: id, Name: after.Name(), From: from, To: to, Changes: changes})
+		}
+	})
+	return results, err
+}
+
+// Shrink is a product whose pack got smaller between two snapshots while its price did not fall
+type Shrink struct {
+	diff.Shrink
+	ProductID string    `json:"id"`
+	Name      string    `json:"name"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+}
+
+// Shrinkflation compares each consecutive pair of snapshots kept since since, and returns
+// those where the pack shrank at the same or a higher price, largest unit price rise first
+func Shrinkflation(db *sql.DB, since time.Time) ([]Shrink, error) {
+	if _, err := Reindex(db); err != nil {
+		return nil, err
+	}
+	shrinks := []Shrink{}
+	err := snapshotPairs(db, "COALESCE(s.fetched_at, 0) >= ?", since.Unix(), func(id string, before, after *product.Product, from, to time.Time) {
+		if shrink, ok := diff.Shrinkflation(before, after); ok {
+			shrinks = append(shrinks, Shrink{Shrink: shrink, ProductID: id, Name: after.Name(), From: from, To: to})
+		}
+	})
+	sort.SliceStable(shrinks, func(i, j int) bool { return shrinks[i].UnitPriceIncrease > shrinks[j].UnitPriceIncrease })
+	return shrinks, err
+}
+
+// snapshotPairs calls fn with each snapshot matching an SQL condition over snapshots,
+// aliased as s, and the snapshot of the same product before it, with their fetch times.
+// Snapshots are ordered by when they were fetched rather than stored, since an import can
+// store an older fetch after a newer one, with an unknown fetch time first.
+func snapshotPairs(db *sql.DB, where string, arg interface{}, fn func(id string, before, after *product.Product, from, to time.Time)) error {
+	rows, err := db.Query(fmt.Sprintf(`SELECT s.product_id, s.prev_raw, s.prev_fetched_at, s.raw, s.fetched_at
+		FROM (SELECT product_id, raw, fetched_at, id,
+				LAG(id) OVER byFetch AS prev_id, LAG(raw) OVER byFetch AS prev_raw, LAG(fetched_at) OVER byFetch AS prev_fetched_at
+			FROM snapshots WINDOW byFetch AS (PARTITION BY product_id ORDER BY COALESCE(fetched_at, 0), id)) s
+		WHERE s.prev_id IS NOT NULL AND %v ORDER BY s.product_id, COALESCE(s.fetched_at, 0), s.id`, where), arg)
+	if err != nil {
+		return fmt.Errorf("failed to query snapshots: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, beforeRaw, afterRaw string
+		var beforeAt, afterAt sql.NullInt64
+		if err := rows.Scan(&id, &beforeRaw, &beforeAt, &afterRaw, &afterAt); err != nil {
+			return fmt.Errorf("failed to scan snapshot: %v", err)
+		}
+		before, err := product.FromResources(beforeRaw, product.IDToURL(id))
+		if err != nil {
+			return fmt.Errorf("failed to parse snapshot of %v: %v", id, err)
+		}
+		after, err := product.FromResources(afterRaw, product.IDToURL(id))
+		if err != nil {
+			return fmt.Errorf("failed to parse snapshot of %v: %v", id, err)
+		}
+		var from, to time.Time
+		if beforeAt.Valid {
+			from = time.Unix(beforeAt.Int64, 0).UTC()
+		}
+		if afterAt.Valid {
+			to = time.Unix(afterAt.Int64, 0).UTC()
+		}
+		fn(id, before, after, from, to)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	db := openTest(t)
+	// the later fetch is recorded first, as imports of older dumps could before they were
+	// skipped
+	for _, r := range []Record{
+		{ID: "300400483", Raw: strings.Replace(steak, `"price":3.55`, `"price":3.95`, 1), FetchedAt: time.Unix(1800000000, 0)},
+		{ID: "300400483", Raw: steak, FetchedAt: time.Unix(1700000000, 0)},
+	} {
+		_, err := db.Exec("INSERT INTO snapshots(product_id, hash, raw, fetched_at) VALUES(?, '', ?, ?)", r.ID, r.Raw, r.FetchedAt.Unix())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	changes, err := History(db, "300400483")
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("History() = %+v, want 1 change", changes)
+	}
+	got := changes[0]
+	if got.From.Unix() != 1700000000 || got.To.Unix() != 1800000000 {
+		t.Errorf("History() from %v to %v, want the older fetch first", got.From.Unix(), got.To.Unix())
+	}
+	if len(got.Changes) != 1 || got.Changes[0].Old != "3.55" || got.Changes[0].New != "3.95" {
+		t.Errorf("History() changes = %+v, want price 3.55 to 3.95", got.Changes)
+	}
+}
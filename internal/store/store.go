@@ -0,0 +1,305 @@
+// Package store persists scraped products to a sqlite3 database and reads them back
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/allergens"
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+// DefaultPath is the database used when none is configured
+const DefaultPath = "./data.db"
+
+// migrations are applied in order, each once, tracked by the sqlite user_version
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS products(id TEXT NOT NULL, source TEXT NOT NULL, raw TEXT NOT NULL)`,
+	`ALTER TABLE products ADD COLUMN fetched_at INTEGER`,
+	// databases written by the insert-only scraper can hold a product many times, so keep
+	// the latest row of each before making them unique
+	`DELETE FROM products WHERE rowid NOT IN (SELECT MAX(rowid) FROM products GROUP BY id, source);
+	CREATE UNIQUE INDEX IF NOT EXISTS products_id_source ON products(id, source)`,
+	`CREATE TABLE product_facts(
+		product_id TEXT PRIMARY KEY,
+		version INTEGER NOT NULL,
+		fetched_at INTEGER,
+		name TEXT,
+		brand TEXT,
+		super_department TEXT,
+		department TEXT,
+		aisle TEXT,
+		shelf TEXT,
+		price REAL,
+		unit_price REAL,
+		unit_of_measure TEXT,
+		price_per_100 REAL,
+		kcal REAL,
+		protein REAL,
+		carbs REAL,
+		fat REAL
+	)`,
+	`CREATE TABLE product_tags(product_id TEXT NOT NULL, tag TEXT NOT NULL, PRIMARY KEY(product_id, tag))`,
+	`CREATE INDEX product_tags_tag ON product_tags(tag)`,
+	// fts4 rather than fts5, which go-sqlite3 only builds with the sqlite_fts5 tag, so a
+	// plain go install could not open the database. Search ranks matches itself with bm25.
+	`CREATE VIRTUAL TABLE product_search USING fts4(title, brand, description, ingredients, tokenize=porter)`,
+	`ALTER TABLE product_facts ADD COLUMN gtin TEXT`,
+	`CREATE INDEX product_facts_gtin ON product_facts(gtin)`,
+	`ALTER TABLE product_facts ADD COLUMN ingredients TEXT`,
+	`ALTER TABLE product_facts ADD COLUMN saturates REAL`,
+	`ALTER TABLE product_facts ADD COLUMN sugars REAL`,
+	`ALTER TABLE product_facts ADD COLUMN fibre REAL`,
+	`ALTER TABLE product_facts ADD COLUMN salt REAL`,
+	`ALTER TABLE product_facts ADD COLUMN nutri_score INTEGER`,
+	`ALTER TABLE product_facts ADD COLUMN nutri_grade TEXT`,
+	`ALTER TABLE product_facts ADD COLUMN fat_light TEXT`,
+	`ALTER TABLE product_facts ADD COLUMN saturates_light TEXT`,
+	`ALTER TABLE product_facts ADD COLUMN sugars_light TEXT`,
+	`ALTER TABLE product_facts ADD COLUMN salt_light TEXT`,
+	`ALTER TABLE product_facts ADD COLUMN pack_size REAL`,
+	`ALTER TABLE product_facts ADD COLUMN pack_unit TEXT`,
+	`ALTER TABLE product_facts ADD COLUMN servings REAL`,
+	`ALTER TABLE product_facts ADD COLUMN pack_kcal REAL`,
+	`ALTER TABLE product_facts ADD COLUMN pack_protein REAL`,
+	`ALTER TABLE product_facts ADD COLUMN serving_kcal REAL`,
+	`ALTER TABLE product_facts ADD COLUMN serving_protein REAL`,
+	`ALTER TABLE product_facts ADD COLUMN cost_per_serving REAL`,
+	`CREATE TABLE promotions(
+		product_id TEXT NOT NULL,
+		promotion_id TEXT NOT NULL,
+		promotion_type TEXT,
+		offer_text TEXT,
+		start_date INTEGER,
+		end_date INTEGER,
+		shelf_price REAL,
+		unit_price REAL,
+		unit_of_measure TEXT,
+		effective_price REAL,
+		effective_unit_price REAL,
+		first_seen INTEGER NOT NULL,
+		last_seen INTEGER NOT NULL,
+		PRIMARY KEY(product_id, promotion_id)
+	)`,
+	`CREATE INDEX promotions_end_date ON promotions(end_date)`,
+	`CREATE TABLE watches(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		product_id TEXT NOT NULL,
+		below REAL,
+		on_promo INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL,
+		observed_at INTEGER,
+		triggered INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE snapshots(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		product_id TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		raw TEXT NOT NULL,
+		fetched_at INTEGER
+	)`,
+	`CREATE INDEX snapshots_product_id ON snapshots(product_id, id)`,
+	`CREATE INDEX snapshots_fetched_at ON snapshots(fetched_at)`,
+	`CREATE TABLE reviews(
+		product_id TEXT NOT NULL,
+		review_id TEXT NOT NULL,
+		rating REAL,
+		rating_range REAL,
+		summary TEXT,
+		text TEXT,
+		source TEXT,
+		submitted_at INTEGER,
+		first_seen INTEGER NOT NULL,
+		PRIMARY KEY(product_id, review_id)
+	)`,
+	`ALTER TABLE product_facts ADD COLUMN rating REAL`,
+	`ALTER TABLE product_facts ADD COLUMN review_count INTEGER`,
+	`CREATE TABLE baskets(name TEXT PRIMARY KEY, created_at INTEGER NOT NULL)`,
+	`CREATE TABLE basket_items(
+		basket TEXT NOT NULL,
+		product_id TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		added_at INTEGER NOT NULL,
+		PRIMARY KEY(basket, product_id)
+	)`,
+}
+
+// Open opens and migrates the sqlite3 database at path
+func Open(path string) (*sql.DB, error) {
+	// wait on locks rather than failing when scrape workers write concurrently
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite3", path+separator+"_busy_timeout=10000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db: %v", err)
+	}
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to connect to db: %v", err)
+	}
+	err = migrate(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate db: %v", err)
+	}
+	return db, nil
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return fmt.Errorf("unable to read schema version: %v", err)
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %v failed: %v", i+1, err)
+		}
+		// PRAGMA does not accept placeholders
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stored is a parsed product along with when it was fetched
+type Stored struct {
+	*product.Product
+	FetchedAt time.Time
+}
+
+// Filter restricts the products returned by Products. Zero values match everything.
+type Filter struct {
+	// Categories matches the super department, department, aisle or shelf name
+	Categories []string
+	Brands     []string
+	// UpdatedSince excludes products fetched before it, or with an unknown fetch time
+	UpdatedSince time.Time
+	// ExcludeAllergens excludes products that contain or may contain any of these allergens
+	ExcludeAllergens []allergens.Allergen
+	// Diets excludes products not labelled as suitable for all of these diets
+	Diets []allergens.Diet
+}
+
+// Match reports whether p satisfies the category, brand, allergen and diet filters
+func (f Filter) Match(p *product.Product) bool {
+	if len(f.Brands) > 0 && !containsFold(f.Brands, p.Brand()) {
+		return false
+	}
+	if len(f.ExcludeAllergens) > 0 || len(f.Diets) > 0 {
+		tags := map[string]bool{}
+		for _, tag := range Tags(p) {
+			tags[tag] = true
+		}
+		for _, tag := range f.excludedTags() {
+			if tags[tag] {
+				return false
+			}
+		}
+		for _, tag := range f.requiredTags() {
+			if !tags[tag] {
+				return false
+			}
+		}
+	}
+	if len(f.Categories) == 0 {
+		return true
+	}
+	for _, category := range p.Categories() {
+		if containsFold(f.Categories, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// byProduct reports whether f has filters that Match needs the parsed product for
+func (f Filter) byProduct() bool {
+	return len(f.Categories) > 0 || len(f.Brands) > 0 || len(f.ExcludeAllergens) > 0 || len(f.Diets) > 0
+}
+
+func (f Filter) excludedTags() []string {
+	tags := []string{}
+	for _, allergen := range f.ExcludeAllergens {
+		tags = append(tags, "allergen:"+string(allergen))
+	}
+	return tags
+}
+
+func (f Filter) requiredTags() []string {
+	tags := []string{}
+	for _, diet := range f.Diets {
+		tags = append(tags, "diet:"+string(diet))
+	}
+	return tags
+}
+
+// TagCondition returns an SQL condition over product_facts, aliased as f, applying the
+// allergen and diet filters, for use with Query
+func (f Filter) TagCondition() (string, []interface{}) {
+	conditions := []string{"1"}
+	args := []interface{}{}
+	for _, tag := range f.excludedTags() {
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM product_tags t WHERE t.product_id = f.product_id AND t.tag = ?)")
+		args = append(args, tag)
+	}
+	for _, tag := range f.requiredTags() {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM product_tags t WHERE t.product_id = f.product_id AND t.tag = ?)")
+		args = append(args, tag)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func containsFold(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if strings.EqualFold(s, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// Products returns the stored products that match filter. Rows that fail to parse are
+// reported on stderr and skipped.
+func Products(db *sql.DB, filter Filter) ([]Stored, error) {
+	query := "SELECT id, raw, fetched_at FROM products WHERE source='product'"
+	args := []interface{}{}
+	if !filter.UpdatedSince.IsZero() {
+		query += " AND fetched_at >= ?"
+		args = append(args, filter.UpdatedSince.Unix())
+	}
+	query += " ORDER BY id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products from DB: %v", err)
+	}
+	defer rows.Close()
+
+	stored, err := scanStored(rows)
+	if err != nil {
+		return nil, err
+	}
+	products := []Stored{}
+	for _, s := range stored {
+		if filter.Match(s.Product) {
+			products = append(products, s)
+		}
+	}
+	return products, nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// steak is the resources json of a product as stored by a scrape
+const steak = `{"productDetails":{"data":{"pageTitle":"Tesco Rump Steak 255G","aisleName":"Fresh Beef","shelfName":"Beef Steaks",` +
+	`"product":{"id":"300400483","brandName":"TESCO","price":3.55,"unitPrice":13.93,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+	`{"name":"Typical Values","perComp":"Per 100g","perServing":"One steak (255g)"},` +
+	`{"name":"Energy","perComp":"715kJ / 171kcal","perServing":"1823kJ / 437kcal"},` +
+	`{"name":"Protein","perComp":"20.3g","perServing":"51.8g"}]}}}}}`
+
+func openTest(t *testing.T) *sql.DB {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func count(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%v: %v", query, err)
+	}
+	return n
+}
+
+func TestSave(t *testing.T) {
+	db := openTest(t)
+	fetchedAt := time.Unix(1700000000, 0)
+	tests := []struct {
+		name        string
+		record      Record
+		wantIndexed bool
+	}{
+		{"parses", Record{ID: "300400483", Raw: steak, FetchedAt: fetchedAt}, true},
+		{"unparseable", Record{ID: "300400484", Raw: `{"productDetails":{}}`, FetchedAt: fetchedAt}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Save(db, tt.record); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if n := count(t, db, "SELECT COUNT(*) FROM products WHERE id = ?", tt.record.ID); n != 1 {
+				t.Errorf("Save() stored %v rows, want 1", n)
+			}
+			indexed := count(t, db, "SELECT COUNT(*) FROM product_facts WHERE product_id = ?", tt.record.ID) == 1
+			if indexed != tt.wantIndexed {
+				t.Errorf("Save() indexed = %v, want %v", indexed, tt.wantIndexed)
+			}
+		})
+	}
+}
+
+func TestOpenMigratesDuplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	// the baseline scraper inserted every fetch without replacing earlier ones
+	for _, stmt := range []string{
+		"CREATE TABLE products(id TEXT NOT NULL, source TEXT NOT NULL, raw TEXT NOT NULL)",
+		"INSERT INTO products(id, source, raw) VALUES('300400483', 'product', 'first'), ('300400483', 'product', 'latest'), ('300400484', 'product', 'only')",
+	} {
+		if _, err := old.Exec(stmt); err != nil {
+			t.Fatalf("%v: %v", stmt, err)
+		}
+	}
+	old.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+	if n := count(t, db, "SELECT COUNT(*) FROM products"); n != 2 {
+		t.Errorf("Open() kept %v products, want 2", n)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM products WHERE id = '300400483' AND raw = 'latest'"); n != 1 {
+		t.Errorf("Open() did not keep the latest row of a duplicated product")
+	}
+}
+
+func TestOpenWithQuery(t *testing.T) {
+	db, err := Open("file:" + filepath.Join(t.TempDir(), "test.db") + "?cache=shared")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	db.Close()
+}
+
+func TestSaveFactsPer100(t *testing.T) {
+	db := openTest(t)
+	tests := []struct {
+		name     string
+		id       string
+		per      string
+		wantKcal float64
+	}{
+		{"per 100g", "300400483", "Per 100g", 171},
+		{"per 50g", "300400484", "Per 50g", 342},
+		{"per 0.1l", "300400485", "Per 0.1l", 171},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := strings.Replace(steak, "Per 100g", tt.per, 1)
+			if err := Save(db, Record{ID: tt.id, Raw: raw, FetchedAt: time.Unix(1700000000, 0)}); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			var kcal float64
+			var fat sql.NullFloat64
+			err := db.QueryRow("SELECT kcal, fat FROM product_facts WHERE product_id = ?", tt.id).Scan(&kcal, &fat)
+			if err != nil {
+				t.Fatalf("failed to read facts: %v", err)
+			}
+			if math.Abs(kcal-tt.wantKcal) > 1e-9 {
+				t.Errorf("kcal = %v, want %v", kcal, tt.wantKcal)
+			}
+			// the steak does not give fat
+			if fat.Valid {
+				t.Errorf("fat = %v, want null", fat.Float64)
+			}
+		})
+	}
+}
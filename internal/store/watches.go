@@ -0,0 +1,133 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/watch"
+)
+
+// AddWatch validates and stores rule, returning it with its ID and creation time set. If
+// the product is already stored, that fetch is the watch's baseline and it is first checked
+// against the next fetch rather than a possibly stale stored price.
+func AddWatch(db *sql.DB, rule watch.Rule) (watch.Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return watch.Rule{}, err
+	}
+	var below interface{}
+	if rule.Below > 0 {
+		below = rule.Below
+	}
+	rule.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	result, err := db.Exec(`INSERT INTO watches(product_id, below, on_promo, created_at, observed_at)
+		VALUES(?, ?, ?, ?, (SELECT COALESCE(fetched_at, 0) FROM products WHERE id = ? AND source = 'product'))`,
+		rule.ProductID, below, rule.OnPromo, rule.CreatedAt.Unix(), rule.ProductID)
+	if err != nil {
+		return watch.Rule{}, fmt.Errorf("failed to add watch: %v", err)
+	}
+	if rule.ID, err = result.LastInsertId(); err != nil {
+		return watch.Rule{}, err
+	}
+	return rule, nil
+}
+
+// RemoveWatch deletes the watch with id, returning an error if there is none
+func RemoveWatch(db *sql.DB, id int64) error {
+	result, err := db.Exec("DELETE FROM watches WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to remove watch: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no watch with ID %v", id)
+	}
+	return nil
+}
+
+// Watches returns every stored watch in the order they were added
+func Watches(db *sql.DB) ([]watch.Rule, error) {
+	rows, err := db.Query("SELECT id, product_id, below, on_promo, created_at FROM watches ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watches: %v", err)
+	}
+	defer rows.Close()
+	rules := []watch.Rule{}
+	for rows.Next() {
+		var rule watch.Rule
+		var below sql.NullFloat64
+		var createdAt int64
+		if err := rows.Scan(&rule.ID, &rule.ProductID, &below, &rule.OnPromo, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watch: %v", err)
+		}
+		rule.Below = below.Float64
+		rule.CreatedAt = time.Unix(createdAt, 0).UTC()
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CheckWatches evaluates each watch against its product if it has been fetched since the
+// watch was last checked, and returns the alerts for watches that have become met. A watch
+// that stays met across fetches alerts once, and again only after it has stopped being met.
+// Nothing is recorded until ack is called, once the alerts have been sent, so alerts that
+// fail to send are returned again by the next check.
+func CheckWatches(db *sql.DB) (alerts []watch.Alert, ack func() error, err error) {
+	rows, err := db.Query(`SELECT w.id, w.product_id, w.below, w.on_promo, w.created_at, w.triggered, p.raw, COALESCE(p.fetched_at, 0)
+		FROM watches w JOIN products p ON p.id = w.product_id AND p.source = 'product'
+		WHERE w.observed_at IS NULL OR COALESCE(p.fetched_at, 0) > w.observed_at
+		ORDER BY w.id`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get watches to check: %v", err)
+	}
+	type checked struct {
+		id         int64
+		observedAt int64
+		met        bool
+	}
+	results := []checked{}
+	alerts = []watch.Alert{}
+	for rows.Next() {
+		var rule watch.Rule
+		var below sql.NullFloat64
+		var createdAt, fetchedAt int64
+		var triggered bool
+		var raw string
+		if err := rows.Scan(&rule.ID, &rule.ProductID, &below, &rule.OnPromo, &createdAt, &triggered, &raw, &fetchedAt); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan watch: %v", err)
+		}
+		rule.Below = below.Float64
+		rule.CreatedAt = time.Unix(createdAt, 0).UTC()
+		p, err := product.FromResources(raw, product.IDToURL(rule.ProductID))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to parse stored product %v: %v\n", rule.ProductID, err)
+			continue
+		}
+		alert, met := rule.Check(watch.Observe(p, time.Unix(fetchedAt, 0).UTC()))
+		if met && !triggered {
+			alerts = append(alerts, alert)
+		}
+		results = append(results, checked{rule.ID, fetchedAt, met})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	ack = func() error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if _, err := tx.Exec("UPDATE watches SET observed_at = ?, triggered = ? WHERE id = ?", result.observedAt, result.met, result.id); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to update watch %v: %v", result.id, err)
+			}
+		}
+		return tx.Commit()
+	}
+	return alerts, ack, nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/watch"
+)
+
+func TestCheckWatches(t *testing.T) {
+	db := openTest(t)
+	fetched := func(day int) Record {
+		return Record{ID: "300400483", Raw: steak, FetchedAt: time.Date(2026, 1, day, 0, 0, 0, 0, time.UTC)}
+	}
+	if err := Save(db, fetched(1)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := AddWatch(db, watch.Rule{ProductID: "300400483", Below: 4}); err != nil {
+		t.Fatalf("AddWatch() error = %v", err)
+	}
+
+	steps := []struct {
+		name      string
+		fetch     int
+		ack       bool
+		wantAlert bool
+	}{
+		{"stored price is the baseline", 0, true, false},
+		{"new fetch below the threshold", 2, false, true},
+		{"unsent alert is returned again", 0, true, true},
+		{"acknowledged alert is not repeated", 0, true, false},
+		{"still met on the next fetch", 3, true, false},
+	}
+	for _, step := range steps {
+		if step.fetch > 0 {
+			if err := Save(db, fetched(step.fetch)); err != nil {
+				t.Fatalf("%v: Save() error = %v", step.name, err)
+			}
+		}
+		alerts, ack, err := CheckWatches(db)
+		if err != nil {
+			t.Fatalf("%v: CheckWatches() error = %v", step.name, err)
+		}
+		if got := len(alerts) == 1; got != step.wantAlert || len(alerts) > 1 {
+			t.Errorf("%v: CheckWatches() alerts = %+v, want alert %v", step.name, alerts, step.wantAlert)
+		}
+		if step.ack {
+			if err := ack(); err != nil {
+				t.Fatalf("%v: ack() error = %v", step.name, err)
+			}
+		}
+	}
+}
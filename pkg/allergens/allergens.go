@@ -0,0 +1,288 @@
+// Package allergens derives the 14 UK regulated allergens and dietary suitability of a product
+// from its ingredients, allergen information, food icons and claims
+package allergens
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattburman/tesco/pkg/ingredients"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/tidwall/gjson"
+)
+
+// Allergen is one of the 14 allergens UK food labels must declare
+type Allergen string
+
+const (
+	Celery      Allergen = "celery"
+	Gluten      Allergen = "gluten"
+	Crustaceans Allergen = "crustaceans"
+	Eggs        Allergen = "eggs"
+	Fish        Allergen = "fish"
+	Lupin       Allergen = "lupin"
+	Milk        Allergen = "milk"
+	Molluscs    Allergen = "molluscs"
+	Mustard     Allergen = "mustard"
+	Nuts        Allergen = "nuts"
+	Peanuts     Allergen = "peanuts"
+	Sesame      Allergen = "sesame"
+	Soya        Allergen = "soya"
+	Sulphites   Allergen = "sulphites"
+)
+
+// All lists the allergens in alphabetical order
+var All = []Allergen{Celery, Crustaceans, Eggs, Fish, Gluten, Lupin, Milk, Molluscs, Mustard, Nuts, Peanuts, Sesame, Soya, Sulphites}
+
+// words are the words on labels that declare each allergen, e.g. wheat for cereals containing gluten
+var words = map[Allergen][]string{
+	Celery:      {"celery", "celeriac"},
+	Gluten:      {"gluten", "wheat", "barley", "rye", "oat", "oats", "spelt", "kamut", "semolina", "durum"},
+	Crustaceans: {"crustaceans?", "prawns?", "shrimps?", "crabs?", "lobsters?", "langoustines?", "crayfish"},
+	Eggs:        {"eggs?"},
+	Fish:        {"fish", "anchov(?:y|ies)", "cod", "haddock", "salmon", "tuna", "mackerel", "pollock", "sardines?", "trout", "hake", "plaice"},
+	Lupin:       {"lupin"},
+	Milk:        {"milk", "cream", "butter", "buttermilk", "cheeses?", "yog(?:h)?urts?", "whey", "lactose", "casein(?:ate)?", "ghee", "dairy"},
+	Molluscs:    {"molluscs?", "mussels?", "oysters?", "squid", "clams?", "scallops?", "octopus", "whelks?"},
+	Mustard:     {"mustard"},
+	Nuts:        {"nuts?", "tree nuts?", "almonds?", "hazelnuts?", "walnuts?", "cashews?", "pecans?", "pistachios?", "macadamias?", "brazils?"},
+	Peanuts:     {"peanuts?", "groundnuts?"},
+	Sesame:      {"sesame"},
+	Soya:        {"soya", "soy", "soybeans?"},
+	Sulphites:   {"sulphites?", "sulfites?", "sulphur dioxide", "metabisulphite"},
+}
+
+var patterns = map[Allergen]*regexp.Regexp{}
+
+func init() {
+	for allergen, alternatives := range words {
+		patterns[allergen] = regexp.MustCompile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)\b`)
+	}
+}
+
+// Lookup returns the allergen named by name, which may be a word declaring it such as wheat
+func Lookup(name string) (Allergen, error) {
+	name = strings.TrimSpace(name)
+	for _, allergen := range All {
+		if patterns[allergen].FindString(name) == name && name != "" {
+			return allergen, nil
+		}
+	}
+	names := make([]string, len(All))
+	for i, allergen := range All {
+		names[i] = string(allergen)
+	}
+	return "", fmt.Errorf("unknown allergen %q, must be one of: %v", name, strings.Join(names, ", "))
+}
+
+// Find returns the allergens declared in text, in alphabetical order
+func Find(text string) []Allergen {
+	found := []Allergen{}
+	for _, allergen := range All {
+		if patterns[allergen].MatchString(text) {
+			found = append(found, allergen)
+		}
+	}
+	return found
+}
+
+// Status is what a product declares about an allergen, in increasing order of precedence
+type Status int
+
+const (
+	Unknown Status = iota
+	FreeFrom
+	MayContain
+	Contains
+)
+
+func (s Status) String() string {
+	switch s {
+	case FreeFrom:
+		return "free-from"
+	case MayContain:
+		return "may-contain"
+	case Contains:
+		return "contains"
+	}
+	return "unknown"
+}
+
+// Diet is a diet a product is labelled as suitable for
+type Diet string
+
+const (
+	Vegetarian Diet = "vegetarian"
+	Vegan      Diet = "vegan"
+	GlutenFree Diet = "gluten-free"
+	DairyFree  Diet = "dairy-free"
+)
+
+// Diets lists the diets
+var Diets = []Diet{Vegetarian, Vegan, GlutenFree, DairyFree}
+
+// ParseDiet returns the diet named by name
+func ParseDiet(name string) (Diet, error) {
+	for _, diet := range Diets {
+		if strings.EqualFold(string(diet), strings.TrimSpace(name)) {
+			return diet, nil
+		}
+	}
+	names := make([]string, len(Diets))
+	for i, diet := range Diets {
+		names[i] = string(diet)
+	}
+	return "", fmt.Errorf("unknown diet %q, must be one of: %v", name, strings.Join(names, ", "))
+}
+
+// Info is the allergen and dietary information of a product
+type Info struct {
+	Allergens map[Allergen]Status
+	Diets     []Diet
+}
+
+// Status returns what the product declares about allergen
+func (i Info) Status(allergen Allergen) Status {
+	return i.Allergens[allergen]
+}
+
+func (i Info) set(allergens []Allergen, status Status) {
+	for _, allergen := range allergens {
+		if status > i.Allergens[allergen] {
+			i.Allergens[allergen] = status
+		}
+	}
+}
+
+func (i Info) hasDiet(diet Diet) bool {
+	for _, d := range i.Diets {
+		if d == diet {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mayContain            = regexp.MustCompile(`(?i)(?:may (?:also )?contain|traces of|(?:made|produced|prepared|packed) (?:in|on) [^.]*?(?:that|which) (?:also )?(?:handles?|uses?))\s+([^.]*)`)
+	freeFrom              = regexp.MustCompile(`(?i)free from\s+([^.]*)`)
+	xFree                 = regexp.MustCompile(`(?i)\b([a-z ]+?)[ -]free\b`)
+	notSuitableVegetarian = regexp.MustCompile(`(?i)not suitable for vegetarians?`)
+	notSuitableVegan      = regexp.MustCompile(`(?i)not suitable for (?:vegetarians?|vegans?)`)
+	vegetarian            = regexp.MustCompile(`(?i)\bvegetarians?\b`)
+	vegan                 = regexp.MustCompile(`(?i)\bvegans?\b`)
+)
+
+// FromProduct derives the allergen and dietary information of p. Allergens emphasised in
+// the ingredients are contained, statements such as "may contain nuts" anywhere in the
+// ingredients, allergen information or warnings mark them as may contain, and claims such
+// as "gluten free" as free from. The strongest statement about each allergen wins.
+func FromProduct(p *product.Product) Info {
+	info := Info{Allergens: map[Allergen]Status{}}
+	details := gjson.Get(p.Raw(), "product.details")
+
+	contained(info, p.Ingredients())
+
+	// allergenInfo is a list of {name, values}, with names such as "Contains" and "May Contain"
+	for _, entry := range details.Get("allergenInfo").Array() {
+		status := Contains
+		name := strings.ToLower(entry.Get("name").String())
+		switch {
+		case strings.Contains(name, "may"):
+			status = MayContain
+		case strings.Contains(name, "free"):
+			status = FreeFrom
+		}
+		for _, value := range entry.Get("values").Array() {
+			info.set(Find(value.String()), status)
+		}
+		if !entry.IsObject() {
+			statements(info, entry.String())
+		}
+	}
+
+	for _, path := range []string{"ingredients", "otherInformation", "warnings", "safetyWarning"} {
+		for _, statement := range texts(details.Get(path)) {
+			statements(info, statement)
+		}
+	}
+
+	claims := []string{}
+	for _, result := range []gjson.Result{
+		gjson.Get(p.Raw(), "product.foodIcons"),
+		details.Get("nutritionalClaims"),
+		details.Get("healthClaims"),
+	} {
+		claims = append(claims, texts(result)...)
+	}
+	for _, claim := range claims {
+		statements(info, claim)
+		for _, match := range xFree.FindAllStringSubmatch(claim, -1) {
+			info.set(Find(match[1]), FreeFrom)
+		}
+	}
+
+	// diets are taken from claims, unless contradicted by the allergens
+	labelled := strings.Join(claims, ". ")
+	// "not suitable for vegans" is usual on vegetarian products such as cheese, so it only
+	// rules out vegan, while not being suitable for vegetarians rules out both
+	animal := info.Status(Fish) == Contains || info.Status(Crustaceans) == Contains || info.Status(Molluscs) == Contains
+	if !notSuitableVegan.MatchString(labelled) && vegan.MatchString(labelled) && !animal &&
+		info.Status(Milk) != Contains && info.Status(Eggs) != Contains {
+		info.Diets = append(info.Diets, Vegan)
+	}
+	if !notSuitableVegetarian.MatchString(labelled) && (vegetarian.MatchString(labelled) || info.hasDiet(Vegan)) && !animal {
+		info.Diets = append(info.Diets, Vegetarian)
+	}
+	if info.Status(Gluten) == FreeFrom {
+		info.Diets = append(info.Diets, GlutenFree)
+	}
+	if info.Status(Milk) == FreeFrom || info.hasDiet(Vegan) {
+		info.Diets = append(info.Diets, DairyFree)
+	}
+	sort.Slice(info.Diets, func(i, j int) bool { return info.Diets[i] < info.Diets[j] })
+	return info
+}
+
+// statements records the may contain and free from statements in text
+func statements(info Info, text string) {
+	for _, match := range mayContain.FindAllStringSubmatch(text, -1) {
+		info.set(Find(match[1]), MayContain)
+	}
+	for _, match := range freeFrom.FindAllStringSubmatch(text, -1) {
+		info.set(Find(match[1]), FreeFrom)
+	}
+}
+
+// contained records the allergens emphasised in an ingredients list, other than those in
+// statements such as "may contain <b>nuts</b>" that are parsed as an ingredient
+func contained(info Info, list []ingredients.Ingredient) {
+	for _, ingredient := range list {
+		if mayContain.MatchString(ingredient.Name) {
+			continue
+		}
+		for _, word := range ingredient.Allergens {
+			info.set(Find(word), Contains)
+		}
+		contained(info, ingredient.Ingredients)
+	}
+}
+
+// texts returns the strings in a string or array of strings result
+func texts(result gjson.Result) []string {
+	if !result.Exists() || result.Type == gjson.Null {
+		return nil
+	}
+	if !result.IsArray() {
+		return []string{result.String()}
+	}
+	strs := []string{}
+	for _, value := range result.Array() {
+		if value.Type == gjson.String {
+			strs = append(strs, value.String())
+		}
+	}
+	return strs
+}
@@ -0,0 +1,104 @@
+package allergens
+
+import (
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestLookup(t *testing.T) {
+	tests := map[string]Allergen{
+		"milk":      Milk,
+		"Wheat":     Gluten,
+		"tree nuts": Nuts,
+		"peanuts":   Peanuts,
+		"soy":       Soya,
+	}
+	for name, want := range tests {
+		got, err := Lookup(name)
+		if err != nil || got != want {
+			t.Errorf("Lookup(%q) got = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"", "coconut", "milk chocolate"} {
+		if _, err := Lookup(name); err == nil {
+			t.Errorf("Lookup(%q) expected an error", name)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	got := Find("Wheat Flour, Peanuts, Nutmeg, Coconut, Sulphur Dioxide")
+	want := []Allergen{Gluten, Peanuts, Sulphites}
+	if diff := pretty.Compare(got, want); diff != "" {
+		t.Errorf("Find() diff: (-got +want)\n%s", diff)
+	}
+}
+
+func TestFromProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		allergens map[Allergen]Status
+		diets     []Diet
+	}{
+		{
+			"ingredients and statements",
+			`{"product":{"foodIcons":["Suitable for Vegetarians"],"details":{
+				"ingredients":["Oats (60%), <b>Milk</b> Powder, Sugar. May contain <b>nuts</b>."],
+				"allergenInfo":[{"name":"May Contain","values":["Peanuts"]}],
+				"nutritionalClaims":["Egg free"]}}}`,
+			map[Allergen]Status{Milk: Contains, Nuts: MayContain, Peanuts: MayContain, Eggs: FreeFrom},
+			[]Diet{Vegetarian},
+		},
+		{
+			"vegan claim",
+			`{"product":{"foodIcons":["Vegan"],"details":{
+				"ingredients":["Water, <b>Soya</b> Beans (12%)"],
+				"healthClaims":["Free From Gluten and Milk"]}}}`,
+			map[Allergen]Status{Soya: Contains, Gluten: FreeFrom, Milk: FreeFrom},
+			[]Diet{DairyFree, GlutenFree, Vegan, Vegetarian},
+		},
+		{
+			"vegetarian claim contradicted",
+			`{"product":{"foodIcons":["Vegetarian"],"details":{"ingredients":["Rice, <b>Anchovy</b>"]}}}`,
+			map[Allergen]Status{Fish: Contains},
+			nil,
+		},
+		{
+			"vegetarian but not vegan",
+			`{"product":{"foodIcons":["Suitable for vegetarians","Not suitable for vegans"],"details":{
+				"ingredients":["Cheddar Cheese (<b>Milk</b>)"]}}}`,
+			map[Allergen]Status{Milk: Contains},
+			[]Diet{Vegetarian},
+		},
+		{
+			"not suitable for vegetarians",
+			`{"product":{"foodIcons":["Vegan friendly packaging","Not suitable for vegetarians"],"details":{"ingredients":["Gelatine"]}}}`,
+			map[Allergen]Status{},
+			nil,
+		},
+		{
+			"no information",
+			`{"product":{"details":{"ingredients":[],"allergenInfo":null}}}`,
+			map[Allergen]Status{},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := product.NewProduct(tt.data, product.IDToURL("300400483"))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			info := FromProduct(p)
+			if diff := pretty.Compare(info.Allergens, tt.allergens); diff != "" {
+				t.Errorf("FromProduct() allergens diff: (-got +want)\n%s", diff)
+			}
+			if diff := pretty.Compare(info.Diets, tt.diets); diff != "" {
+				t.Errorf("FromProduct() diets diff: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
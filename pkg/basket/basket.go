@@ -0,0 +1,121 @@
+// Package basket totals the price and nutrition of a basket of whole packs of products,
+// with running offers applied
+package basket
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/export"
+	"github.com/mattburman/tesco/pkg/nutrition"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/promotion"
+)
+
+// Formats lists the formats a basket can be exported in
+var Formats = []string{export.CSV, export.JSONL}
+
+// Item is a number of packs of a product
+type Item struct {
+	Product  *product.Product
+	Quantity int
+}
+
+// Line is the price and nutrition of an item
+type Line struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Quantity int    `json:"quantity"`
+	// ShelfPrice is the cost of Quantity packs at the shelf price, and Price with the
+	// cheapest running offer applied
+	ShelfPrice float64 `json:"shelfPrice"`
+	Price      float64 `json:"price"`
+	Offer      string  `json:"offer,omitempty"`
+	// Nutrients are those of Quantity whole packs, nil when the pack size or nutrition is unknown
+	Nutrients *nutrition.Nutrients `json:"nutrients"`
+}
+
+// Totals are the lines of a basket and their totals
+type Totals struct {
+	Lines      []Line              `json:"lines"`
+	ShelfPrice float64             `json:"shelfPrice"`
+	Price      float64             `json:"price"`
+	Saving     float64             `json:"saving"`
+	Nutrients  nutrition.Nutrients `json:"nutrients"`
+	// Days is the number of days the basket is to last, and PerDay the nutrients and price
+	// for each of them, when Days is set
+	Days   int                  `json:"days,omitempty"`
+	PerDay *nutrition.Nutrients `json:"perDay,omitempty"`
+	// PricePerDay is Price over Days
+	PricePerDay float64 `json:"pricePerDay,omitempty"`
+	// Missing lists the products left out of Nutrients
+	Missing []string `json:"missing"`
+}
+
+// Total prices items with the cheapest offer running at at, and totals their nutrition.
+// Days of 0 or less leaves out the per-day figures.
+func Total(items []Item, at time.Time, days int) Totals {
+	totals := Totals{Lines: []Line{}, Missing: []string{}}
+	for _, item := range items {
+		p := item.Product
+		line := Line{ID: p.ID(), Name: p.Name(), URL: p.URL(), Quantity: item.Quantity}
+		line.ShelfPrice = p.Price() * float64(item.Quantity)
+		line.Price = line.ShelfPrice
+		for _, offer := range promotion.FromProduct(p) {
+			if !offer.Active(at) {
+				continue
+			}
+			if cost, ok := offer.Cost(p.Price(), item.Quantity); ok && cost < line.Price {
+				line.Price, line.Offer = cost, offer.Text
+			}
+		}
+		if perPack, ok := p.PerPack(); ok {
+			nutrients := nutrition.Nutrients{}.Add(nutrition.FromMacros(perPack), float64(item.Quantity))
+			line.Nutrients = &nutrients
+			totals.Nutrients = totals.Nutrients.Add(nutrients, 1)
+		} else {
+			totals.Missing = append(totals.Missing, p.Name())
+		}
+		totals.ShelfPrice += line.ShelfPrice
+		totals.Price += line.Price
+		totals.Lines = append(totals.Lines, line)
+	}
+	totals.Saving = totals.ShelfPrice - totals.Price
+	if days > 0 {
+		perDay := nutrition.Nutrients{}.Add(totals.Nutrients, 1/float64(days))
+		totals.Days, totals.PerDay, totals.PricePerDay = days, &perDay, totals.Price/float64(days)
+	}
+	return totals
+}
+
+// Write writes the lines of a basket as a shopping list in format, one of Formats
+func Write(w io.Writer, format string, totals Totals) error {
+	switch format {
+	case export.CSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "name", "quantity", "shelf_price", "price", "offer", "url"})
+		for _, l := range totals.Lines {
+			cw.Write([]string{
+				l.ID, l.Name, strconv.Itoa(l.Quantity),
+				strconv.FormatFloat(l.ShelfPrice, 'f', 2, 64), strconv.FormatFloat(l.Price, 'f', 2, 64),
+				l.Offer, l.URL,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case export.JSONL:
+		enc := json.NewEncoder(w)
+		for _, l := range totals.Lines {
+			if err := enc.Encode(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported basket format %q", format)
+}
@@ -0,0 +1,95 @@
+package basket
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/export"
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestTotal(t *testing.T) {
+	chicken, err := product.NewProduct(`{"pageTitle":"Tesco Chicken Breast 650G","product":{"id":"100000001","title":"Tesco Chicken Breast 650G","price":3.9,"details":{`+
+		`"packSize":[{"value":"650","units":"g"}],"nutritionInfo":[`+
+		`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},`+
+		`{"name":"Energy","perComp":"440kJ / 104kcal","perServing":"-"},`+
+		`{"name":"Protein","perComp":"24.0g","perServing":"-"}]}},`+
+		`"promotions":[{"promotionId":"A1","offerText":"Any 2 for £6.00","startDate":"2019-11-01T00:00:00Z","endDate":"2019-11-30T00:00:00Z"}]}`,
+		product.IDToURL("100000001"))
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	water, err := product.NewProduct(`{"pageTitle":"Still Water 2L","product":{"id":"100000002","title":"Still Water 2L","price":0.5}}`,
+		product.IDToURL("100000002"))
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	items := []Item{{chicken, 3}, {water, 2}}
+
+	tests := []struct {
+		name       string
+		at         time.Time
+		days       int
+		wantPrice  string
+		wantSaving string
+		wantOffer  string
+		wantPerDay string
+		wantCSV    string
+	}{
+		{
+			"during the offer", time.Date(2019, 11, 20, 0, 0, 0, 0, time.UTC), 2,
+			"10.90", "1.80", "Any 2 for £6.00", "5.45 234g",
+			"id,name,quantity,shelf_price,price,offer,url\n" +
+				"100000001,Tesco Chicken Breast 650G,3,11.70,9.90,Any 2 for £6.00,https://www.tesco.com/groceries/en-GB/products/100000001\n" +
+				"100000002,Still Water 2L,2,1.00,1.00,,https://www.tesco.com/groceries/en-GB/products/100000002\n",
+		},
+		{
+			// no days leaves out the per-day figures
+			"after the offer", time.Date(2019, 12, 20, 0, 0, 0, 0, time.UTC), 0,
+			"12.70", "0.00", "", "",
+			"id,name,quantity,shelf_price,price,offer,url\n" +
+				"100000001,Tesco Chicken Breast 650G,3,11.70,11.70,,https://www.tesco.com/groceries/en-GB/products/100000001\n" +
+				"100000002,Still Water 2L,2,1.00,1.00,,https://www.tesco.com/groceries/en-GB/products/100000002\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Total(items, tt.at, tt.days)
+			if len(got.Lines) != 2 {
+				t.Fatalf("Total() got %v lines, want 2", len(got.Lines))
+			}
+			if price, saving := fmt.Sprintf("%.2f", got.Price), fmt.Sprintf("%.2f", got.Saving); price != tt.wantPrice || saving != tt.wantSaving {
+				t.Errorf("Total() price, saving = %v, %v, want %v, %v", price, saving, tt.wantPrice, tt.wantSaving)
+			}
+			if got.Lines[0].Offer != tt.wantOffer {
+				t.Errorf("Total() chicken offer = %q, want %q", got.Lines[0].Offer, tt.wantOffer)
+			}
+			if l := got.Lines[0]; l.Nutrients == nil || fmt.Sprintf("%.0f", l.Nutrients.Protein) != "468" {
+				t.Errorf("Total() chicken nutrients = %+v, want 468g protein", l.Nutrients)
+			}
+			if kcal := fmt.Sprintf("%.0f", got.Nutrients.Kcal); kcal != "2028" {
+				t.Errorf("Total() kcal = %v, want 2028", kcal)
+			}
+			perDay := ""
+			if got.PerDay != nil {
+				perDay = fmt.Sprintf("%.2f %.0fg", got.PricePerDay, got.PerDay.Protein)
+			}
+			if perDay != tt.wantPerDay {
+				t.Errorf("Total() per day = %q, want %q", perDay, tt.wantPerDay)
+			}
+			if len(got.Missing) != 1 || got.Missing[0] != "Still Water 2L" {
+				t.Errorf("Total() missing = %v", got.Missing)
+			}
+
+			var b bytes.Buffer
+			if err := Write(&b, export.CSV, got); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if b.String() != tt.wantCSV {
+				t.Errorf("Write() = %q, want %q", b.String(), tt.wantCSV)
+			}
+		})
+	}
+}
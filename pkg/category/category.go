@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"github.com/gocolly/colly"
 	"github.com/mattburman/tesco/pkg/collecting"
+	"github.com/mattburman/tesco/pkg/metrics"
 	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/progress"
 	"github.com/tidwall/gjson"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 )
 
 type ProductResult struct {
@@ -65,65 +68,115 @@ func AddCountToURL(u string) (string, error) {
 	return u, nil
 }
 
-func Scrape(url string, concurrency int, productResults chan ProductResult, db *sql.DB) error {
+// Scrape visits a category URL and places each product not yet in the DB on productResults.
+// Progress is recorded in stats, which may be nil. productResults is closed on return.
+func Scrape(url string, concurrency int, productResults chan ProductResult, db *sql.DB, stats *progress.Stats) error {
+	defer close(productResults)
 	url, err := AddCountToURL(url)
 	if err != nil {
 		return fmt.Errorf("unable to parse url: %v", err)
 	}
+	stats.AddCategory()
 
-	productCollector := colly.NewCollector(
-		colly.Async(true),
-	)
-	productCollector.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: concurrency})
-	productCollector.OnError(func(r *colly.Response, err error) {
-		fmt.Println("Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
-	})
-	productCollector.OnHTML("[data-props]", func(e *colly.HTMLElement) {
-		productJson, err := collecting.ExtractResources(e.Attr("data-props"))
-		if err != nil {
-			fmt.Printf("error extracting resources from data-props: %v\n", err)
-			return
-		}
-		url := e.Request.URL.String()
-		id, err := product.URLToID(url)
-		if err != nil {
-			fmt.Printf("could not get id from url: %v", err)
-		}
-		productResults <- ProductResult{Id: id, Url: url, Json: *productJson}
-	})
+	productCollector := NewProductCollector(concurrency, productResults, stats)
 
 	categoryCollector := colly.NewCollector(
 		colly.Async(true),
 	)
+	metrics.Instrument(categoryCollector, "category")
+	categoryCollector.OnResponse(func(r *colly.Response) {
+		stats.AddPage()
+	})
 	categoryCollector.OnHTML("[data-props]", func(e *colly.HTMLElement) {
 		categoryJson, err := collecting.ExtractResources(e.Attr("data-props"))
 		if err != nil {
-			fmt.Printf("error extracting resources from data-props: %v\n", err)
+			stats.AddFailed()
+			metrics.ParseFailures.WithLabelValues("data-props").Inc()
+			fmt.Fprintf(os.Stderr, "error extracting resources from data-props: %v\n", err)
+			return
 		}
 
 		productIDs, err := ToProductIDs(categoryJson)
 		if err != nil {
-			fmt.Printf("error extracting productIDs: %v\n", err)
+			metrics.ParseFailures.WithLabelValues("productIDs").Inc()
+			fmt.Fprintf(os.Stderr, "error extracting productIDs: %v\n", err)
 			return
 		}
 
-		unfetchedProductIDs, err := product.GetUnfetchedProductIDs(db, productIDs)
-		if err != nil {
-			fmt.Printf("failed to get unfetched productResults from DB: %v", err)
-		}
-
-		for _, productID := range *unfetchedProductIDs {
-			productCollector.Visit(fmt.Sprintf("https://www.tesco.com/groceries/en-GB/products/%v", productID))
+		if err := VisitUnfetched(productCollector, db, productIDs, stats); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
 		}
 		productCollector.Wait()
 	})
 	categoryCollector.OnError(func(r *colly.Response, err error) {
-		fmt.Println("Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
+		stats.AddFailed()
+		fmt.Fprintln(os.Stderr, "Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
 	})
 
 	categoryCollector.Visit(url)
 	categoryCollector.Wait()
-	close(productResults)
+	return nil
+}
+
+// NewProductCollector returns an async collector that places each product page it visits
+// on productResults. Progress is recorded in stats, which may be nil.
+func NewProductCollector(concurrency int, productResults chan ProductResult, stats *progress.Stats) *colly.Collector {
+	productCollector := colly.NewCollector(
+		colly.Async(true),
+	)
+	productCollector.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: concurrency})
+	metrics.Instrument(productCollector, "product")
+	productCollector.OnError(func(r *colly.Response, err error) {
+		stats.AddFailed()
+		fmt.Fprintln(os.Stderr, "Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
+	})
+	productCollector.OnHTML("[data-props]", func(e *colly.HTMLElement) {
+		productJson, err := collecting.ExtractResources(e.Attr("data-props"))
+		if err != nil {
+			stats.AddFailed()
+			metrics.ParseFailures.WithLabelValues("data-props").Inc()
+			fmt.Fprintf(os.Stderr, "error extracting resources from data-props: %v\n", err)
+			return
+		}
+		url := e.Request.URL.String()
+		id, err := product.URLToID(url)
+		if err != nil {
+			metrics.ParseFailures.WithLabelValues("id").Inc()
+			fmt.Fprintf(os.Stderr, "could not get id from url: %v\n", err)
+		}
+		stats.AddFetched()
+		metrics.QueueDepth.WithLabelValues("productResults").Inc()
+		productResults <- ProductResult{Id: id, Url: url, Json: *productJson}
+	})
+	return productCollector
+}
+
+// VisitUnfetched queues a visit on productCollector for each of productIDs not yet in the DB
+func VisitUnfetched(productCollector *colly.Collector, db *sql.DB, productIDs *[]string, stats *progress.Stats) error {
+	unfetchedProductIDs, err := product.GetUnfetchedProductIDs(db, productIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get unfetched productResults from DB: %v", err)
+	}
+	stats.AddSkipped(len(*productIDs) - len(*unfetchedProductIDs))
+	stats.AddQueued(len(*unfetchedProductIDs))
+
+	for _, productID := range *unfetchedProductIDs {
+		productCollector.Visit(product.IDToURL(productID))
+	}
+	return nil
+}
+
+// ScrapeIDs visits the page of each of productIDs, whether or not it is already in the DB,
+// and places each product on productResults. productResults is closed on return.
+func ScrapeIDs(productIDs []string, concurrency int, productResults chan ProductResult, stats *progress.Stats) error {
+	defer close(productResults)
+	productCollector := NewProductCollector(concurrency, productResults, stats)
+	stats.AddQueued(len(productIDs))
+	for _, productID := range productIDs {
+		productCollector.Visit(product.IDToURL(productID))
+	}
+	productCollector.Wait()
 	return nil
 }
 
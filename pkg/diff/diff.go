@@ -0,0 +1,108 @@
+// Package diff compares two snapshots of a product field by field, to spot reformulations,
+// price changes and shrinkflation
+package diff
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mattburman/tesco/pkg/ingredients"
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+// Change is a field whose value differs between two snapshots of a product
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Products returns the changes from before to after in title, brand, barcode, price, pack size,
+// nutrients and ingredients. Unchanged fields are left out.
+func Products(before, after *product.Product) []Change {
+	changes := []Change{}
+	text := func(field, o, n string) {
+		if strings.TrimSpace(o) != strings.TrimSpace(n) {
+			changes = append(changes, Change{Field: field, Old: o, New: n})
+		}
+	}
+	number := func(field string, o, n float64, unit string) {
+		if o != n {
+			changes = append(changes, Change{Field: field, Old: formatAmount(o, unit), New: formatAmount(n, unit)})
+		}
+	}
+
+	text("title", before.Name(), after.Name())
+	text("brand", before.Brand(), after.Brand())
+	text("gtin", before.GTIN(), after.GTIN())
+	number("price", before.Price(), after.Price(), "")
+	number("unit price", before.UnitPrice(), after.UnitPrice(), "")
+	text("unit of measure", before.UnitOfMeasure(), after.UnitOfMeasure())
+	text("pack size", formatAmount(before.Pack().Size(), before.Pack().Unit()), formatAmount(after.Pack().Size(), after.Pack().Unit()))
+	text("net contents", before.Pack().NetContents(), after.Pack().NetContents())
+	number("servings", before.Servings(), after.Servings(), "")
+
+	// nutrients are compared per the comparison size, noting if that itself changed
+	beforeComp, afterComp := before.PerComp(), after.PerComp()
+	text("per", beforeComp.Per(), afterComp.Per())
+	number("kcal", beforeComp.Kcal(), afterComp.Kcal(), "")
+	number("protein", beforeComp.Protein(), afterComp.Protein(), "g")
+	number("carbs", beforeComp.Carbs(), afterComp.Carbs(), "g")
+	number("sugars", beforeComp.Sugars(), afterComp.Sugars(), "g")
+	number("fat", beforeComp.Fat(), afterComp.Fat(), "g")
+	number("saturates", beforeComp.Saturates(), afterComp.Saturates(), "g")
+	number("fibre", beforeComp.Fibre(), afterComp.Fibre(), "g")
+	number("salt", beforeComp.Salt(), afterComp.Salt(), "g")
+
+	text("ingredients", List(before.Ingredients()), List(after.Ingredients()))
+	return changes
+}
+
+// List formats a list of ingredients as it would appear on a label
+func List(list []ingredients.Ingredient) string {
+	formatted := make([]string, len(list))
+	for i, ingredient := range list {
+		formatted[i] = ingredient.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func formatAmount(f float64, unit string) string {
+	if f == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64) + unit
+}
+
+// Shrink is a pack that got smaller while its price stayed the same or rose
+type Shrink struct {
+	OldSize  float64 `json:"oldSize"`
+	NewSize  float64 `json:"newSize"`
+	Unit     string  `json:"unit"`
+	OldPrice float64 `json:"oldPrice"`
+	NewPrice float64 `json:"newPrice"`
+	// UnitPriceIncrease is the fractional rise in price per g or ml, e.g. 0.2 for 20%
+	UnitPriceIncrease float64 `json:"unitPriceIncrease"`
+}
+
+// Shrinkflation reports whether after's pack is smaller than before's, in the same unit,
+// at the same or a higher price
+func Shrinkflation(before, after *product.Product) (Shrink, bool) {
+	oldPack, newPack := before.Pack(), after.Pack()
+	if oldPack.Size() <= 0 || newPack.Size() <= 0 || oldPack.Unit() != newPack.Unit() {
+		return Shrink{}, false
+	}
+	if newPack.Size() >= oldPack.Size() || before.Price() <= 0 || after.Price() < before.Price() {
+		return Shrink{}, false
+	}
+	oldUnitPrice := before.Price() / oldPack.Size()
+	newUnitPrice := after.Price() / newPack.Size()
+	return Shrink{
+		OldSize:           oldPack.Size(),
+		NewSize:           newPack.Size(),
+		Unit:              newPack.Unit(),
+		OldPrice:          before.Price(),
+		NewPrice:          after.Price(),
+		UnitPriceIncrease: newUnitPrice/oldUnitPrice - 1,
+	}, true
+}
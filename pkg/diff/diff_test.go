@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestProducts(t *testing.T) {
+	chicken := `{"pageTitle":"Tesco Chicken Breast 650G","product":{"price":3.9,"details":{` +
+		`"packSize":[{"value":"650","units":"g"}],"ingredients":["Chicken Breast (100%)"],"nutritionInfo":[` +
+		`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},` +
+		`{"name":"Energy","perComp":"400kJ / 95kcal","perServing":"-"},` +
+		`{"name":"Protein","perComp":"24.0g","perServing":"-"}]}}}`
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []Change
+	}{
+		{
+			"shrunk with more water",
+			chicken,
+			`{"pageTitle":"Tesco Chicken Breast 600G","product":{"price":3.9,"details":{` +
+				`"packSize":[{"value":"600","units":"g"}],"ingredients":["Chicken Breast (97%), Water, Salt"],"nutritionInfo":[` +
+				`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},` +
+				`{"name":"Energy","perComp":"400kJ / 95kcal","perServing":"-"},` +
+				`{"name":"Protein","perComp":"23.5g","perServing":"-"}]}}}`,
+			[]Change{
+				{"title", "Tesco Chicken Breast 650G", "Tesco Chicken Breast 600G"},
+				{"pack size", "650g", "600g"},
+				{"protein", "24g", "23.5g"},
+				{"ingredients", "Chicken Breast (100%)", "Chicken Breast (97%), Water, Salt"},
+			},
+		},
+		{"unchanged", chicken, chicken, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := product.NewProduct(tt.before, product.IDToURL("100000001"))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			after, err := product.NewProduct(tt.after, product.IDToURL("100000001"))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			got := Products(before, after)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if diff := pretty.Compare(got, tt.want); diff != "" {
+				t.Errorf("Products() diff: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestShrinkflation(t *testing.T) {
+	tests := []struct {
+		name         string
+		before       string
+		after        string
+		want         bool
+		wantIncrease float64
+	}{
+		{
+			"smaller at same price",
+			`{"pageTitle":"Crisps","product":{"price":2,"details":{"packSize":[{"value":"200","units":"g"}]}}}`,
+			`{"pageTitle":"Crisps","product":{"price":2,"details":{"packSize":[{"value":"160","units":"g"}]}}}`,
+			true, 0.25,
+		},
+		{
+			"smaller at higher price",
+			`{"pageTitle":"Crisps","product":{"price":2,"details":{"packSize":[{"value":"200","units":"g"}]}}}`,
+			`{"pageTitle":"Crisps","product":{"price":2.2,"details":{"packSize":[{"value":"160","units":"g"}]}}}`,
+			true, 0.375,
+		},
+		{
+			"smaller at lower price",
+			`{"pageTitle":"Crisps","product":{"price":2,"details":{"packSize":[{"value":"200","units":"g"}]}}}`,
+			`{"pageTitle":"Crisps","product":{"price":1.6,"details":{"packSize":[{"value":"160","units":"g"}]}}}`,
+			false, 0,
+		},
+		{
+			"bigger",
+			`{"pageTitle":"Crisps","product":{"price":2,"details":{"packSize":[{"value":"160","units":"g"}]}}}`,
+			`{"pageTitle":"Crisps","product":{"price":2,"details":{"packSize":[{"value":"200","units":"g"}]}}}`,
+			false, 0,
+		},
+		{
+			"unchanged",
+			`{"pageTitle":"Crisps","product":{"price":2,"details":{"packSize":[{"value":"200","units":"g"}]}}}`,
+			`{"pageTitle":"Crisps","product":{"price":2.5,"details":{"packSize":[{"value":"200","units":"g"}]}}}`,
+			false, 0,
+		},
+		{
+			"different units",
+			`{"pageTitle":"Cola","product":{"price":2,"details":{"packSize":[{"value":"2000","units":"g"}]}}}`,
+			`{"pageTitle":"Cola","product":{"price":2,"details":{"packSize":[{"value":"1750","units":"ml"}]}}}`,
+			false, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := product.NewProduct(tt.before, product.IDToURL("100000001"))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			after, err := product.NewProduct(tt.after, product.IDToURL("100000001"))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			got, ok := Shrinkflation(before, after)
+			if ok != tt.want || math.Abs(got.UnitPriceIncrease-tt.wantIncrease) > 1e-9 {
+				t.Errorf("Shrinkflation() = %+v, %v, want increase %v, %v", got, ok, tt.wantIncrease, tt.want)
+			}
+		})
+	}
+}
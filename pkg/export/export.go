@@ -0,0 +1,165 @@
+// Package export writes flattened products for spreadsheets and data warehouse loads
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+const (
+	CSV     = "csv"
+	JSONL   = "jsonl"
+	Parquet = "parquet"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{CSV, JSONL, Parquet}
+
+// Record is a product flattened to a single row of nutrients and prices
+type Record struct {
+	ID              string  `json:"id" parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Name            string  `json:"name" parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Brand           string  `json:"brand" parquet:"name=brand, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Department      string  `json:"department" parquet:"name=department, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Aisle           string  `json:"aisle" parquet:"name=aisle, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Shelf           string  `json:"shelf" parquet:"name=shelf, type=BYTE_ARRAY, convertedtype=UTF8"`
+	URL             string  `json:"url" parquet:"name=url, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Price           float64 `json:"price" parquet:"name=price, type=DOUBLE"`
+	UnitPrice       float64 `json:"unitPrice" parquet:"name=unit_price, type=DOUBLE"`
+	UnitOfMeasure   string  `json:"unitOfMeasure" parquet:"name=unit_of_measure, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Per             string  `json:"per" parquet:"name=per, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PerSize         float64 `json:"perSize" parquet:"name=per_size, type=DOUBLE"`
+	PerUnit         string  `json:"perUnit" parquet:"name=per_unit, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Kcal            float64 `json:"kcal" parquet:"name=kcal, type=DOUBLE"`
+	Protein         float64 `json:"protein" parquet:"name=protein, type=DOUBLE"`
+	Carbs           float64 `json:"carbs" parquet:"name=carbs, type=DOUBLE"`
+	Fat             float64 `json:"fat" parquet:"name=fat, type=DOUBLE"`
+	Serving         string  `json:"serving" parquet:"name=serving, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ServingSize     float64 `json:"servingSize" parquet:"name=serving_size, type=DOUBLE"`
+	ServingUnit     string  `json:"servingUnit" parquet:"name=serving_unit, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ServingKcal     float64 `json:"servingKcal" parquet:"name=serving_kcal, type=DOUBLE"`
+	ServingProtein  float64 `json:"servingProtein" parquet:"name=serving_protein, type=DOUBLE"`
+	ServingCarbs    float64 `json:"servingCarbs" parquet:"name=serving_carbs, type=DOUBLE"`
+	ServingFat      float64 `json:"servingFat" parquet:"name=serving_fat, type=DOUBLE"`
+	PackSize        float64 `json:"packSize" parquet:"name=pack_size, type=DOUBLE"`
+	PackUnit        string  `json:"packUnit" parquet:"name=pack_unit, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Servings        float64 `json:"servings" parquet:"name=servings, type=DOUBLE"`
+	PackKcal        float64 `json:"packKcal" parquet:"name=pack_kcal, type=DOUBLE"`
+	PackProtein     float64 `json:"packProtein" parquet:"name=pack_protein, type=DOUBLE"`
+	CostPerServing  float64 `json:"costPerServing" parquet:"name=cost_per_serving, type=DOUBLE"`
+	FetchedAtMillis int64   `json:"-" parquet:"name=fetched_at, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	FetchedAt       string  `json:"fetchedAt"`
+}
+
+// NewRecord flattens p, fetched at fetchedAt, into a Record. A zero fetchedAt is left empty.
+func NewRecord(p *product.Product, fetchedAt time.Time) Record {
+	perComp, perServing := p.PerComp(), p.PerServing()
+	r := Record{
+		ID:             p.ID(),
+		Name:           p.Name(),
+		Brand:          p.Brand(),
+		Department:     p.Department(),
+		Aisle:          p.Aisle(),
+		Shelf:          p.Shelf(),
+		URL:            p.URL(),
+		Price:          p.Price(),
+		UnitPrice:      p.UnitPrice(),
+		UnitOfMeasure:  p.UnitOfMeasure(),
+		Per:            perComp.Per(),
+		PerSize:        perComp.Size(),
+		PerUnit:        perComp.Unit(),
+		Kcal:           perComp.Kcal(),
+		Protein:        perComp.Protein(),
+		Carbs:          perComp.Carbs(),
+		Fat:            perComp.Fat(),
+		Serving:        perServing.Per(),
+		ServingSize:    perServing.Size(),
+		ServingUnit:    perServing.Unit(),
+		ServingKcal:    perServing.Kcal(),
+		ServingProtein: perServing.Protein(),
+		ServingCarbs:   perServing.Carbs(),
+		ServingFat:     perServing.Fat(),
+		PackSize:       p.Pack().Size(),
+		PackUnit:       p.Pack().Unit(),
+		Servings:       p.Servings(),
+	}
+	if perPack, ok := p.PerPack(); ok {
+		r.PackKcal, r.PackProtein = perPack.Kcal(), perPack.Protein()
+	}
+	if cost, ok := p.CostPerServing(); ok {
+		r.CostPerServing = cost
+	}
+	if !fetchedAt.IsZero() {
+		r.FetchedAtMillis = fetchedAt.UnixNano() / int64(time.Millisecond)
+		r.FetchedAt = fetchedAt.UTC().Format(time.RFC3339)
+	}
+	return r
+}
+
+// Write writes records to w in format
+func Write(w io.Writer, format string, records []Record) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, records)
+	case JSONL:
+		return writeJSONL(w, records)
+	case Parquet:
+		return writeParquet(w, records)
+	}
+	return fmt.Errorf("unsupported export format %q, must be one of: %v", format, strings.Join(Formats, ", "))
+}
+
+var header = []string{
+	"id", "name", "brand", "department", "aisle", "shelf", "url",
+	"price", "unit_price", "unit_of_measure",
+	"per", "per_size", "per_unit", "kcal", "protein", "carbs", "fat",
+	"serving", "serving_size", "serving_unit", "serving_kcal", "serving_protein", "serving_carbs", "serving_fat",
+	"pack_size", "pack_unit", "servings", "pack_kcal", "pack_protein", "cost_per_serving",
+	"fetched_at",
+}
+
+func (r Record) values() []string {
+	return []string{
+		r.ID, r.Name, r.Brand, r.Department, r.Aisle, r.Shelf, r.URL,
+		formatFloat(r.Price), formatFloat(r.UnitPrice), r.UnitOfMeasure,
+		r.Per, formatFloat(r.PerSize), r.PerUnit, formatFloat(r.Kcal), formatFloat(r.Protein), formatFloat(r.Carbs), formatFloat(r.Fat),
+		r.Serving, formatFloat(r.ServingSize), r.ServingUnit, formatFloat(r.ServingKcal), formatFloat(r.ServingProtein), formatFloat(r.ServingCarbs), formatFloat(r.ServingFat),
+		formatFloat(r.PackSize), r.PackUnit, formatFloat(r.Servings), formatFloat(r.PackKcal), formatFloat(r.PackProtein), formatFloat(r.CostPerServing),
+		r.FetchedAt,
+	}
+}
+
+func writeCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := cw.Write(r.values()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONL(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("unable to encode %v: %v", r.ID, err)
+		}
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestWrite(t *testing.T) {
+	raw := `{"pageTitle":"Tesco Semi Skimmed Milk 2.272L","aisleName":"Milk","product":{"price":1.45,"unitPrice":0.64,"unitOfMeasure":"litre","brandName":"TESCO"}}`
+	p, err := product.NewProduct(raw, "https://www.tesco.com/groceries/en-GB/products/254918073")
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	records := []Record{NewRecord(p, time.Date(2019, 11, 5, 12, 0, 0, 0, time.UTC))}
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			CSV,
+			"id,name,brand,department,aisle,shelf,url,price,unit_price,unit_of_measure,per,per_size,per_unit,kcal,protein,carbs,fat,serving,serving_size,serving_unit,serving_kcal,serving_protein,serving_carbs,serving_fat,pack_size,pack_unit,servings,pack_kcal,pack_protein,cost_per_serving,fetched_at\n" +
+				"254918073,Tesco Semi Skimmed Milk 2.272L,TESCO,,Milk,,https://www.tesco.com/groceries/en-GB/products/254918073,1.45,0.64,litre,,0,,0,0,0,0,,0,,0,0,0,0,0,,0,0,0,0,2019-11-05T12:00:00Z\n",
+			false,
+		},
+		{
+			JSONL,
+			`{"id":"254918073","name":"Tesco Semi Skimmed Milk 2.272L","brand":"TESCO","department":"","aisle":"Milk","shelf":"","url":"https://www.tesco.com/groceries/en-GB/products/254918073","price":1.45,"unitPrice":0.64,"unitOfMeasure":"litre","per":"","perSize":0,"perUnit":"","kcal":0,"protein":0,"carbs":0,"fat":0,"serving":"","servingSize":0,"servingUnit":"","servingKcal":0,"servingProtein":0,"servingCarbs":0,"servingFat":0,"packSize":0,"packUnit":"","servings":0,"packKcal":0,"packProtein":0,"costPerServing":0,"fetchedAt":"2019-11-05T12:00:00Z"}` + "\n",
+			false,
+		},
+		{
+			"xlsx",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Write(&buf, tt.format, records)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Write() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Write() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
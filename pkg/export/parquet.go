@@ -0,0 +1,26 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+func writeParquet(w io.Writer, records []Record) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(Record), 4)
+	if err != nil {
+		return fmt.Errorf("unable to create parquet writer: %v", err)
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	for _, r := range records {
+		if err := pw.Write(r); err != nil {
+			return fmt.Errorf("unable to write %v: %v", r.ID, err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return fmt.Errorf("unable to finish parquet file: %v", err)
+	}
+	return nil
+}
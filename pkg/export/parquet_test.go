@@ -0,0 +1,31 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestWriteParquet(t *testing.T) {
+	raw := `{"pageTitle":"Tesco Semi Skimmed Milk 2.272L","aisleName":"Milk","product":{"price":1.45,"unitPrice":0.64,"unitOfMeasure":"litre","brandName":"TESCO"}}`
+	p, err := product.NewProduct(raw, "https://www.tesco.com/groceries/en-GB/products/254918073")
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Parquet, []Record{NewRecord(p, time.Date(2019, 11, 5, 12, 0, 0, 0, time.UTC))}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got := buf.Bytes()
+	// a parquet file starts and ends with the magic bytes, and its footer names each column
+	if !bytes.HasPrefix(got, []byte("PAR1")) || !bytes.HasSuffix(got, []byte("PAR1")) {
+		t.Errorf("Write() did not write a parquet file: %q", got)
+	}
+	for _, column := range header {
+		if !bytes.Contains(got, []byte(column)) {
+			t.Errorf("Write() has no column %v", column)
+		}
+	}
+}
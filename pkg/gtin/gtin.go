@@ -0,0 +1,78 @@
+// Package gtin validates and normalises GTIN barcodes, as printed on product packaging
+package gtin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Length is the number of digits in a normalised GTIN
+const Length = 14
+
+// Normalize checks that code is a GTIN-8, GTIN-12 (UPC-A), GTIN-13 (EAN) or GTIN-14 with
+// a valid check digit and returns it as 14 digits, padded with leading zeros.
+// Spaces and hyphens are ignored, as are leading zeros beyond 14 digits. A code of 9 to 11
+// digits, such as one which lost its leading zeros when read as a number, is padded too,
+// and as leading zeros do not change the check digit it decides whether the code is valid.
+func Normalize(code string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code)
+	if digits == "" {
+		return "", fmt.Errorf("empty GTIN")
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("GTIN %q must only contain digits", code)
+		}
+	}
+	if extra := len(digits) - Length; extra > 0 && strings.Trim(digits[:extra], "0") == "" {
+		digits = digits[extra:]
+	}
+	if len(digits) < 8 || len(digits) > Length {
+		return "", fmt.Errorf("GTIN %q has %v digits, must be 8 to 14", code, len(digits))
+	}
+	padded := strings.Repeat("0", Length-len(digits)) + digits
+	if want := CheckDigit(padded[:Length-1]); padded[Length-1] != want {
+		return "", fmt.Errorf("GTIN %q has an invalid check digit, expected %c", code, want)
+	}
+	return padded, nil
+}
+
+// Valid reports whether code is a GTIN with a valid check digit
+func Valid(code string) bool {
+	_, err := Normalize(code)
+	return err == nil
+}
+
+// CheckDigit returns the GS1 mod 10 check digit for the digits of a GTIN preceding it.
+// From the right, digits are weighted 3, 1, 3, ... so leading zeros do not change it.
+func CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Short returns the shortest common form of a normalised GTIN: 8 digits for a GTIN-8,
+// otherwise 13 when it has a leading zero, as printed under an EAN-13 barcode
+func Short(gtin string) string {
+	if len(gtin) != Length {
+		return gtin
+	}
+	if strings.HasPrefix(gtin, "000000") {
+		return gtin[6:]
+	}
+	if gtin[0] == '0' {
+		return gtin[1:]
+	}
+	return gtin
+}
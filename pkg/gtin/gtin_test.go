@@ -0,0 +1,53 @@
+package gtin
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{"05057545713815", "05057545713815", false},
+		{"5057545713815", "05057545713815", false},
+		{"5057 5457 1381 5", "05057545713815", false},
+		{"0005057545713815", "05057545713815", false},
+		{"96385074", "00000096385074", false},
+		{"036000291452", "00036000291452", false},
+		{"36000291452", "00036000291452", false},
+		{"123456784", "00000123456784", false},
+		{"123456789", "", true},
+		{"0000000000036000291452", "00036000291452", false},
+		{"10012345678902", "10012345678902", false},
+		{"5057545713816", "", true},
+		{"505754571381a", "", true},
+		{"1234567", "", true},
+		{"123456789012345", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := Normalize(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Normalize() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShort(t *testing.T) {
+	tests := map[string]string{
+		"05057545713815": "5057545713815",
+		"00000096385074": "96385074",
+		"10012345678902": "10012345678902",
+	}
+	for gtin, want := range tests {
+		if got := Short(gtin); got != want {
+			t.Errorf("Short(%v) got = %v, want %v", gtin, got, want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package ingredients parses the ingredients lists of Tesco products.
+//
+// Tesco gives ingredients as HTML, with allergens emphasised in <strong> or <b> tags:
+//
+//	INGREDIENTS: Beef (95%), Water, Rusk (<b>Wheat</b> Flour (Calcium Carbonate, Iron), Salt), Salt.
+//
+// which Parse turns into an ordered list of ingredients with their declared percentages,
+// allergens and any sub-ingredients.
+package ingredients
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Ingredient is an entry in an ingredients list
+type Ingredient struct {
+	Name string `json:"name"`
+	// Percent is the declared quantity, e.g. 95 for "Beef (95%)"
+	Percent *float64 `json:"percent,omitempty"`
+	// Allergens are the emphasised words of the name, in lower case
+	Allergens   []string     `json:"allergens,omitempty"`
+	Ingredients []Ingredient `json:"ingredients,omitempty"`
+}
+
+var (
+	emphasis    = regexp.MustCompile(`(?i)<(?:strong|b)>(.*?)</(?:strong|b)>`)
+	emphasisTag = regexp.MustCompile(`(?i)<(/?)(?:strong|b)>`)
+	tags        = regexp.MustCompile(`<[^>]*>`)
+	label       = regexp.MustCompile(`(?i)^\s*ingredients?\s*:\s*`)
+	percent     = regexp.MustCompile(`^(?:min(?:imum)?\.?\s*)?(\d+(?:[.,]\d+)?)\s*%$`)
+	trailing    = regexp.MustCompile(`\s+(\d+(?:[.,]\d+)?)\s*%$`)
+	spaces      = regexp.MustCompile(`\s+`)
+)
+
+// Parse parses the lines of an ingredients list, as found in product.details.ingredients
+func Parse(lines []string) []Ingredient {
+	list := []Ingredient{}
+	for _, line := range lines {
+		line = label.ReplaceAllString(strings.TrimSpace(line), "")
+		list = append(list, parseList(line)...)
+	}
+	return list
+}
+
+// parseList parses a comma separated list of ingredients, which may contain HTML
+func parseList(s string) []Ingredient {
+	list := []Ingredient{}
+	for _, item := range balance(split(strings.TrimRight(strings.TrimSpace(s), "."))) {
+		if ingredient, ok := parseItem(item); ok {
+			list = append(list, ingredient)
+		}
+	}
+	return list
+}
+
+// parseItem parses an ingredient with an optional percentage and parenthesised sub-ingredients,
+// e.g. "Beef (95%)", "Pork 90%" or "Rusk (Wheat Flour, Salt)"
+func parseItem(item string) (Ingredient, bool) {
+	ingredient := Ingredient{}
+	name := strings.TrimSpace(item)
+	if p, ok := parsePercent(name); ok {
+		ingredient.Percent = &p
+		name = ""
+	} else if match := trailing.FindStringSubmatch(text(name)); match != nil {
+		if p, ok := parsePercent(match[1] + "%"); ok {
+			ingredient.Percent = &p
+			name = name[:strings.LastIndex(name, match[1])]
+		}
+	}
+	for {
+		open, close := trailingGroup(name)
+		if open < 0 {
+			break
+		}
+		inner := strings.TrimSpace(name[open+1 : close])
+		name = strings.TrimSpace(name[:open]) + name[close+1:]
+		if p, ok := parsePercent(inner); ok {
+			ingredient.Percent = &p
+			continue
+		}
+		sub := parseList(inner)
+		// a percentage may lead the sub-ingredients, as in "Cheese (20%, Milk)"
+		if len(sub) > 0 && sub[0].Percent != nil && sub[0].Name == "" {
+			ingredient.Percent = sub[0].Percent
+			sub = sub[1:]
+		}
+		ingredient.Ingredients = append(sub, ingredient.Ingredients...)
+	}
+	for _, match := range emphasis.FindAllStringSubmatch(name, -1) {
+		if allergen := strings.ToLower(text(match[1])); allergen != "" {
+			ingredient.Allergens = append(ingredient.Allergens, allergen)
+		}
+	}
+	ingredient.Name = text(name)
+	if ingredient.Name == "" && ingredient.Percent == nil && len(ingredient.Ingredients) == 0 {
+		return ingredient, false
+	}
+	return ingredient, true
+}
+
+// trailingGroup returns the positions of the last top level bracketed group in s
+// if s ends with it, or -1
+func trailingGroup(s string) (int, int) {
+	s = strings.TrimRight(s, " ")
+	if s == "" || (s[len(s)-1] != ')' && s[len(s)-1] != ']') {
+		return -1, -1
+	}
+	depth := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case ')', ']':
+			depth++
+		case '(', '[':
+			depth--
+			if depth == 0 {
+				return i, len(s) - 1
+			}
+		}
+	}
+	return -1, -1
+}
+
+func parsePercent(s string) (float64, bool) {
+	match := percent.FindStringSubmatch(strings.ToLower(text(s)))
+	if match == nil {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	return p, err == nil
+}
+
+// split splits s on commas and semicolons outside brackets, other than decimal commas
+func split(s string) []string {
+	items := []string{}
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth > 0 {
+				depth--
+			}
+		case ',', ';':
+			if depth > 0 || (c == ',' && i > 0 && i+1 < len(s) && isDigit(s[i-1]) && isDigit(s[i+1])) {
+				continue
+			}
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+// balance closes emphasis left open at the end of an item and reopens it in the next, for
+// allergens emphasised across a comma as in "<b>Milk, Egg</b>"
+func balance(items []string) []string {
+	open := false
+	for i, item := range items {
+		if open {
+			item = "<b>" + item
+		}
+		for _, tag := range emphasisTag.FindAllStringSubmatch(item, -1) {
+			open = tag[1] == ""
+		}
+		if open {
+			item += "</b>"
+		}
+		items[i] = item
+	}
+	return items
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// text strips HTML from s and collapses whitespace
+func text(s string) string {
+	s = html.UnescapeString(tags.ReplaceAllString(s, ""))
+	return strings.TrimSpace(spaces.ReplaceAllString(s, " "))
+}
+
+// Allergens returns the distinct allergens emphasised anywhere in list, in order of appearance
+func Allergens(list []Ingredient) []string {
+	allergens := []string{}
+	seen := map[string]bool{}
+	var walk func([]Ingredient)
+	walk = func(list []Ingredient) {
+		for _, ingredient := range list {
+			for _, allergen := range ingredient.Allergens {
+				if !seen[allergen] {
+					seen[allergen] = true
+					allergens = append(allergens, allergen)
+				}
+			}
+			walk(ingredient.Ingredients)
+		}
+	}
+	walk(list)
+	return allergens
+}
+
+// allergenPatterns caches the pattern matching each allergen as a whole word, by allergen
+var allergenPatterns sync.Map
+
+func allergenPattern(allergen string) *regexp.Regexp {
+	if re, ok := allergenPatterns.Load(allergen); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, _ := allergenPatterns.LoadOrStore(allergen, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(allergen)+`\b`))
+	return re.(*regexp.Regexp)
+}
+
+// String formats an ingredient as it would appear on a label, with allergens in upper case
+func (i Ingredient) String() string {
+	name := i.Name
+	for _, allergen := range i.Allergens {
+		name = allergenPattern(allergen).ReplaceAllStringFunc(name, strings.ToUpper)
+	}
+	if i.Percent != nil {
+		name += " (" + strconv.FormatFloat(*i.Percent, 'f', -1, 64) + "%)"
+	}
+	if len(i.Ingredients) > 0 {
+		sub := make([]string, len(i.Ingredients))
+		for j, ingredient := range i.Ingredients {
+			sub[j] = ingredient.String()
+		}
+		name += " (" + strings.Join(sub, ", ") + ")"
+	}
+	return name
+}
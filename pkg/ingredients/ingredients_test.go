@@ -0,0 +1,105 @@
+package ingredients
+
+import (
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func pct(p float64) *float64 {
+	return &p
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []Ingredient
+	}{
+		{
+			"percentages",
+			[]string{"INGREDIENTS: Beef (95%), Water, Pork 2.5%, Salt."},
+			[]Ingredient{
+				{Name: "Beef", Percent: pct(95)},
+				{Name: "Water"},
+				{Name: "Pork", Percent: pct(2.5)},
+				{Name: "Salt"},
+			},
+		},
+		{
+			"nested with allergens",
+			[]string{"Rusk (<strong>Wheat</strong> Flour (Calcium Carbonate, Iron), Salt), Cheese (20%, <b>Milk</b>), Sauce [Water; Tomato (8%)]"},
+			[]Ingredient{
+				{Name: "Rusk", Ingredients: []Ingredient{
+					{Name: "Wheat Flour", Allergens: []string{"wheat"}, Ingredients: []Ingredient{
+						{Name: "Calcium Carbonate"},
+						{Name: "Iron"},
+					}},
+					{Name: "Salt"},
+				}},
+				{Name: "Cheese", Percent: pct(20), Ingredients: []Ingredient{
+					{Name: "Milk", Allergens: []string{"milk"}},
+				}},
+				{Name: "Sauce", Ingredients: []Ingredient{
+					{Name: "Water"},
+					{Name: "Tomato", Percent: pct(8)},
+				}},
+			},
+		},
+		{
+			"percentage and sub-ingredients",
+			[]string{"Pesto (12%) (Basil, <b>Pine Nuts</b>), Oil (Rapeseed, Sunflower) 1,5%"},
+			[]Ingredient{
+				{Name: "Pesto", Percent: pct(12), Ingredients: []Ingredient{
+					{Name: "Basil"},
+					{Name: "Pine Nuts", Allergens: []string{"pine nuts"}},
+				}},
+				{Name: "Oil", Percent: pct(1.5), Ingredients: []Ingredient{
+					{Name: "Rapeseed"},
+					{Name: "Sunflower"},
+				}},
+			},
+		},
+		{
+			"emphasis across a comma",
+			[]string{"Flour (<b>Wheat, Barley</b>), <strong>Milk, Egg</strong> Powder, Salt"},
+			[]Ingredient{
+				{Name: "Flour", Ingredients: []Ingredient{
+					{Name: "Wheat", Allergens: []string{"wheat"}},
+					{Name: "Barley", Allergens: []string{"barley"}},
+				}},
+				{Name: "Milk", Allergens: []string{"milk"}},
+				{Name: "Egg Powder", Allergens: []string{"egg"}},
+				{Name: "Salt"},
+			},
+		},
+		{
+			"empty",
+			[]string{},
+			[]Ingredient{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := pretty.Compare(Parse(tt.lines), tt.want); diff != "" {
+				t.Errorf("Parse() diff: (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAllergens(t *testing.T) {
+	list := Parse([]string{"<b>Milk</b>, Rusk (<b>Wheat</b> Flour, <b>Milk</b> Powder), <b>Egg</b>"})
+	got := Allergens(list)
+	want := []string{"milk", "wheat", "egg"}
+	if diff := pretty.Compare(got, want); diff != "" {
+		t.Errorf("Allergens() diff: (-got +want)\n%s", diff)
+	}
+}
+
+func TestString(t *testing.T) {
+	list := Parse([]string{"Cheese (20%, <b>Milk</b>)"})
+	if got, want := list[0].String(), "Cheese (20%) (MILK)"; got != want {
+		t.Errorf("String() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,397 @@
+// Package lp solves small linear and integer programs with the simplex method and
+// branch and bound
+package lp
+
+import (
+	"errors"
+	"math"
+)
+
+// Op is the relation of a constraint's left side to its right side
+type Op int
+
+const (
+	// LE is <=
+	LE Op = iota
+	// GE is >=
+	GE
+	// EQ is =
+	EQ
+)
+
+// Constraint is a linear constraint Coeffs·x Op RHS. Coeffs may be shorter than the
+// number of variables, with missing coefficients taken as 0.
+type Constraint struct {
+	Coeffs []float64
+	Op     Op
+	RHS    float64
+}
+
+// Problem minimises Objective·x subject to Constraints, with every variable x >= 0
+type Problem struct {
+	Objective   []float64
+	Constraints []Constraint
+	// Upper bounds each variable, ignored when 0 or missing
+	Upper []float64
+	// Integer marks variables that must take whole values in SolveInteger
+	Integer []bool
+}
+
+// Solution is the values of the variables at an optimum and the objective there
+type Solution struct {
+	X         []float64
+	Objective float64
+	// Optimal is false when SolveInteger stopped at its node limit with a feasible but
+	// possibly suboptimal solution
+	Optimal bool
+}
+
+var (
+	// ErrInfeasible is returned when no x satisfies every constraint
+	ErrInfeasible = errors.New("no solution satisfies every constraint")
+	// ErrUnbounded is returned when the objective can decrease without limit
+	ErrUnbounded = errors.New("objective is unbounded")
+)
+
+const eps = 1e-9
+
+// Solve returns an optimum of the linear relaxation, ignoring Integer
+func (p Problem) Solve() (Solution, error) {
+	x, objective, _, err := p.relax(p.bounds())
+	if err != nil {
+		return Solution{}, err
+	}
+	return Solution{X: x, Objective: objective, Optimal: true}, nil
+}
+
+// bounds are the lower and upper bound of each variable, and whether the upper bound is
+// added as a constraint
+type bounds struct {
+	lower, upper []float64
+	active       []bool
+}
+
+// SolveInteger returns an optimum with every Integer variable whole, by branch and bound
+// over the linear relaxation. After maxNodes relaxations it returns the best solution found
+// so far, or ErrInfeasible if there is none.
+func (p Problem) SolveInteger(maxNodes int) (Solution, error) {
+	best := Solution{Objective: math.Inf(1)}
+	found := false
+	// nodes narrow the bounds of the variables branched on, and are explored depth first
+	stack := []bounds{p.bounds()}
+	for nodes := 0; len(stack) > 0 && nodes < maxNodes; nodes++ {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		x, objective, active, err := p.relax(node)
+		if err == ErrInfeasible {
+			continue
+		}
+		if err != nil {
+			return Solution{}, err
+		}
+		if found && objective >= best.Objective-1e-7 {
+			continue
+		}
+
+		branch, fraction := -1, 0.0
+		for j, integer := range p.Integer {
+			if j >= len(x) || !integer {
+				continue
+			}
+			f := x[j] - math.Floor(x[j])
+			if distance := math.Min(f, 1-f); distance > 1e-6 && distance > fraction {
+				branch, fraction = j, distance
+			}
+		}
+		if branch < 0 {
+			for j, integer := range p.Integer {
+				if integer && j < len(x) {
+					x[j] = math.Round(x[j])
+				}
+			}
+			best, found = Solution{X: x, Objective: objective}, true
+			continue
+		}
+
+		// the rounded up branch is pushed last to be explored first, as covering minimum
+		// targets needs rounding up
+		down := bounds{node.lower, append([]float64{}, node.upper...), append([]bool{}, active...)}
+		down.upper[branch] = math.Floor(x[branch])
+		down.active[branch] = true
+		up := bounds{append([]float64{}, node.lower...), node.upper, active}
+		up.lower[branch] = math.Ceil(x[branch])
+		stack = append(stack, down, up)
+	}
+	if !found {
+		return Solution{}, ErrInfeasible
+	}
+	best.Optimal = len(stack) == 0
+	return best, nil
+}
+
+// bounds returns the bounds of the variables, with Upper bounds of 0 infinite
+func (p Problem) bounds() bounds {
+	n := len(p.Objective)
+	b := bounds{make([]float64, n), make([]float64, n), make([]bool, n)}
+	for j := range b.upper {
+		b.upper[j] = math.Inf(1)
+		if j < len(p.Upper) && p.Upper[j] > 0 {
+			b.upper[j] = p.Upper[j]
+		}
+	}
+	return b
+}
+
+// relax minimises the objective subject to the constraints and bounds, returning which upper
+// bounds were needed. Lower bounds are substituted out and variables fixed by their bounds
+// removed. Upper bounds are added as constraints when active or once violated, so bounding
+// many variables stays cheap.
+func (p Problem) relax(b bounds) ([]float64, float64, []bool, error) {
+	n := len(p.Objective)
+	objective := append([]float64{}, p.Objective...)
+	fixed := make([]bool, n)
+	for j := range b.upper {
+		if b.upper[j] < b.lower[j]-1e-9 {
+			return nil, 0, nil, ErrInfeasible
+		}
+		if b.upper[j] <= b.lower[j]+1e-9 {
+			fixed[j], objective[j] = true, 0
+		}
+	}
+	constraints := make([]Constraint, 0, len(p.Constraints))
+	for _, con := range p.Constraints {
+		coeffs := make([]float64, len(con.Coeffs))
+		rhs := con.RHS
+		for j, coeff := range con.Coeffs {
+			if j < n {
+				rhs -= coeff * b.lower[j]
+				if !fixed[j] {
+					coeffs[j] = coeff
+				}
+			}
+		}
+		constraints = append(constraints, Constraint{Coeffs: coeffs, Op: con.Op, RHS: rhs})
+	}
+	active := append([]bool{}, b.active...)
+	addBound := func(j int) {
+		bound := make([]float64, j+1)
+		bound[j] = 1
+		constraints = append(constraints, Constraint{Coeffs: bound, Op: LE, RHS: b.upper[j] - b.lower[j]})
+		active[j] = true
+	}
+	for j := range active {
+		if active[j] && !fixed[j] && !math.IsInf(b.upper[j], 1) {
+			addBound(j)
+		}
+	}
+
+	for {
+		x, _, err := simplex(objective, constraints)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		violated := false
+		for j := range x {
+			if !fixed[j] && x[j]+b.lower[j] > b.upper[j]+1e-7 {
+				addBound(j)
+				violated = true
+			}
+		}
+		if violated {
+			continue
+		}
+		total := 0.0
+		for j := range x {
+			if fixed[j] {
+				x[j] = 0
+			}
+			x[j] += b.lower[j]
+			total += p.Objective[j] * x[j]
+		}
+		return x, total, active, nil
+	}
+}
+
+// tableau is a simplex tableau of m constraint rows over n columns, with the right hand
+// side in column n, and the basic column of each row
+type tableau struct {
+	rows  [][]float64
+	basis []int
+	n     int
+}
+
+func (t *tableau) pivot(row, col int) {
+	pivot := t.rows[row]
+	f := pivot[col]
+	for j := range pivot {
+		pivot[j] /= f
+	}
+	for i, r := range t.rows {
+		if i == row || r[col] == 0 {
+			continue
+		}
+		g := r[col]
+		for j := range r {
+			r[j] -= g * pivot[j]
+		}
+	}
+	t.basis[row] = col
+}
+
+// optimise pivots until cost can not be reduced by any allowed column, returning false if
+// it is unbounded. Entering columns have the most negative reduced cost, switching to
+// Bland's rule after a run of degenerate pivots to avoid cycling.
+func (t *tableau) optimise(cost []float64, allowed func(int) bool) bool {
+	reduced := make([]float64, t.n)
+	for j := range reduced {
+		reduced[j] = cost[j]
+		for i, r := range t.rows {
+			reduced[j] -= cost[t.basis[i]] * r[j]
+		}
+	}
+	degenerate := 0
+	for {
+		enter, lowest := -1, -eps
+		for j, r := range reduced {
+			if r < lowest && allowed(j) {
+				enter, lowest = j, r
+				if degenerate > 50 {
+					break
+				}
+			}
+		}
+		if enter < 0 {
+			return true
+		}
+
+		leave, ratio := -1, math.Inf(1)
+		for i, r := range t.rows {
+			if r[enter] <= eps {
+				continue
+			}
+			q := r[t.n] / r[enter]
+			if q < ratio-eps || (q < ratio+eps && t.basis[i] < t.basis[leave]) {
+				leave, ratio = i, q
+			}
+		}
+		if leave < 0 {
+			return false
+		}
+		if ratio < eps {
+			degenerate++
+		} else {
+			degenerate = 0
+		}
+		t.pivot(leave, enter)
+		f := reduced[enter]
+		for j := range reduced {
+			reduced[j] -= f * t.rows[leave][j]
+		}
+	}
+}
+
+// simplex minimises c·x subject to constraints and x >= 0 with the two phase method
+func simplex(c []float64, constraints []Constraint) ([]float64, float64, error) {
+	n := len(c)
+	// right hand sides are made non-negative so that slacks and artificials start feasible
+	rows := make([]Constraint, len(constraints))
+	slacks, artificials := 0, 0
+	for i, con := range constraints {
+		coeffs := make([]float64, n)
+		copy(coeffs, con.Coeffs)
+		op, rhs := con.Op, con.RHS
+		if rhs < 0 {
+			for j := range coeffs {
+				coeffs[j] = -coeffs[j]
+			}
+			rhs = -rhs
+			if op == LE {
+				op = GE
+			} else if op == GE {
+				op = LE
+			}
+		}
+		if op != EQ {
+			slacks++
+		}
+		if op != LE {
+			artificials++
+		}
+		rows[i] = Constraint{Coeffs: coeffs, Op: op, RHS: rhs}
+	}
+
+	width := n + slacks + artificials
+	t := &tableau{basis: make([]int, len(rows)), n: width}
+	slack, artificial := n, n+slacks
+	for i, con := range rows {
+		row := make([]float64, width+1)
+		copy(row, con.Coeffs)
+		row[width] = con.RHS
+		switch con.Op {
+		case LE:
+			row[slack] = 1
+			t.basis[i] = slack
+			slack++
+		case GE:
+			row[slack] = -1
+			slack++
+			fallthrough
+		case EQ:
+			row[artificial] = 1
+			t.basis[i] = artificial
+			artificial++
+		}
+		t.rows = append(t.rows, row)
+	}
+	isArtificial := func(j int) bool { return j >= n+slacks }
+
+	if artificials > 0 {
+		phase1 := make([]float64, width)
+		for j := n + slacks; j < width; j++ {
+			phase1[j] = 1
+		}
+		t.optimise(phase1, func(int) bool { return true })
+		infeasibility, scale := 0.0, 1.0
+		for i, b := range t.basis {
+			if isArtificial(b) {
+				infeasibility += t.rows[i][width]
+			}
+		}
+		for _, con := range constraints {
+			scale = math.Max(scale, math.Abs(con.RHS))
+		}
+		if infeasibility > 1e-7*scale {
+			return nil, 0, ErrInfeasible
+		}
+		// artificials left basic at zero are pivoted out, or left if their row is redundant
+		for i, b := range t.basis {
+			if !isArtificial(b) {
+				continue
+			}
+			for j := 0; j < n+slacks; j++ {
+				if math.Abs(t.rows[i][j]) > eps {
+					t.pivot(i, j)
+					break
+				}
+			}
+		}
+	}
+
+	cost := make([]float64, width)
+	copy(cost, c)
+	if !t.optimise(cost, func(j int) bool { return !isArtificial(j) }) {
+		return nil, 0, ErrUnbounded
+	}
+	x := make([]float64, n)
+	for i, b := range t.basis {
+		if b < n {
+			x[b] = math.Max(0, t.rows[i][width])
+		}
+	}
+	objective := 0.0
+	for j, v := range x {
+		objective += c[j] * v
+	}
+	return x, objective, nil
+}
@@ -0,0 +1,132 @@
+package lp
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem Problem
+		want    []float64
+		wantObj float64
+		wantErr error
+	}{
+		{
+			// maximise 3x + 5y as minimising its negation
+			name: "maximise",
+			problem: Problem{
+				Objective: []float64{-3, -5},
+				Constraints: []Constraint{
+					{[]float64{1, 0}, LE, 4},
+					{[]float64{0, 2}, LE, 12},
+					{[]float64{3, 2}, LE, 18},
+				},
+			},
+			want:    []float64{2, 6},
+			wantObj: -36,
+		},
+		{
+			name: "upper bounds",
+			problem: Problem{
+				Objective:   []float64{-3, -5},
+				Constraints: []Constraint{{[]float64{3, 2}, LE, 18}},
+				Upper:       []float64{4, 6},
+			},
+			want:    []float64{2, 6},
+			wantObj: -36,
+		},
+		{
+			name: "diet",
+			problem: Problem{
+				Objective: []float64{0.6, 0.35},
+				Constraints: []Constraint{
+					{[]float64{5, 7}, GE, 8},
+					{[]float64{4, 2}, GE, 15},
+					{[]float64{2, 1}, GE, 3},
+				},
+			},
+			want:    []float64{3.75, 0},
+			wantObj: 2.25,
+		},
+		{
+			name: "equality and negative right hand side",
+			problem: Problem{
+				Objective: []float64{1, 2},
+				Constraints: []Constraint{
+					{[]float64{1, 1}, EQ, 10},
+					{[]float64{-1}, LE, -4},
+				},
+			},
+			want:    []float64{10, 0},
+			wantObj: 10,
+		},
+		{
+			name: "infeasible",
+			problem: Problem{
+				Objective: []float64{1},
+				Constraints: []Constraint{
+					{[]float64{1}, GE, 5},
+					{[]float64{1}, LE, 3},
+				},
+			},
+			wantErr: ErrInfeasible,
+		},
+		{
+			name: "unbounded",
+			problem: Problem{
+				Objective:   []float64{-1, 0},
+				Constraints: []Constraint{{[]float64{1, -1}, LE, 1}},
+			},
+			wantErr: ErrUnbounded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.problem.Solve()
+			if err != tt.wantErr {
+				t.Fatalf("Solve() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !near(got.Objective, tt.wantObj) {
+				t.Errorf("Solve() objective = %v, want %v", got.Objective, tt.wantObj)
+			}
+			for j := range tt.want {
+				if !near(got.X[j], tt.want[j]) {
+					t.Errorf("Solve() x = %v, want %v", got.X, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSolveInteger(t *testing.T) {
+	// the relaxation is x = 3.75 at a cost of 2.25, which must round to whole servings
+	problem := Problem{
+		Objective: []float64{0.6, 0.35},
+		Constraints: []Constraint{
+			{[]float64{5, 7}, GE, 8},
+			{[]float64{4, 2}, GE, 15},
+			{[]float64{2, 1}, GE, 3},
+		},
+		Integer: []bool{true, true},
+	}
+	got, err := problem.SolveInteger(1000)
+	if err != nil {
+		t.Fatalf("SolveInteger() error = %v", err)
+	}
+	if !got.Optimal || !near(got.X[0], 4) || !near(got.X[1], 0) || !near(got.Objective, 2.4) {
+		t.Errorf("SolveInteger() = %+v, want x = [4 0] at 2.4", got)
+	}
+
+	problem.Constraints = append(problem.Constraints, Constraint{[]float64{1, 1}, LE, 2})
+	if _, err := problem.SolveInteger(1000); err != ErrInfeasible {
+		t.Errorf("SolveInteger() error = %v, want %v", err, ErrInfeasible)
+	}
+}
@@ -0,0 +1,109 @@
+// Package metrics exposes Prometheus metrics for long-running scrapes
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const startKey = "metricsStart"
+
+var (
+	// HTTPRequests counts completed requests by collector and status code
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tesco",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests made by collector and response status.",
+	}, []string{"collector", "status"})
+
+	// FetchLatency observes the time taken to fetch a page by collector
+	FetchLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "tesco",
+		Name:      "fetch_duration_seconds",
+		Help:      "Time taken to fetch a page, including time queued behind the parallelism limit.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"collector"})
+
+	// ParseFailures counts failures to extract a field from a response
+	ParseFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tesco",
+		Name:      "parse_failures_total",
+		Help:      "Failures to extract data from a response by field.",
+	}, []string{"field"})
+
+	// InsertLatency observes the time taken to insert a product into the DB
+	InsertLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tesco",
+		Name:      "db_insert_duration_seconds",
+		Help:      "Time taken to insert a product into the database.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 12),
+	})
+
+	// QueueDepth is the number of items waiting in each queue
+	QueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tesco",
+		Name:      "queue_depth",
+		Help:      "Requests pending in each collector, or products waiting on the productResults channel.",
+	}, []string{"queue"})
+)
+
+func init() {
+	prometheus.MustRegister(HTTPRequests, FetchLatency, ParseFailures, InsertLatency, QueueDepth)
+}
+
+// Serve exposes the registered metrics at /metrics on addr in the background.
+// An error is returned if addr cannot be listened on.
+func Serve(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %v: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			fmt.Fprintf(os.Stderr, "metrics server stopped: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// Instrument records request counts, latency and queue depth for a collector under name
+func Instrument(c *colly.Collector, name string) {
+	pending := QueueDepth.WithLabelValues(name)
+	c.OnRequest(func(r *colly.Request) {
+		pending.Inc()
+		r.Ctx.Put(startKey, time.Now())
+	})
+	c.OnResponse(func(r *colly.Response) {
+		observe(r, name)
+	})
+	c.OnError(func(r *colly.Response, err error) {
+		observe(r, name)
+	})
+}
+
+// observe records a finished request once, as colly may report errors after a response
+func observe(r *colly.Response, name string) {
+	start, ok := r.Ctx.GetAny(startKey).(time.Time)
+	if !ok {
+		return
+	}
+	r.Ctx.Put(startKey, nil)
+
+	status := "error"
+	if r.StatusCode != 0 {
+		status = strconv.Itoa(r.StatusCode)
+	}
+	QueueDepth.WithLabelValues(name).Dec()
+	HTTPRequests.WithLabelValues(name, status).Inc()
+	FetchLatency.WithLabelValues(name).Observe(time.Since(start).Seconds())
+}
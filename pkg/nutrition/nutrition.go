@@ -0,0 +1,279 @@
+// Package nutrition computes UK front-of-pack traffic lights and the Nutri-Score of products
+package nutrition
+
+import (
+	"regexp"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+// Nutrients are amounts per 100g or 100ml, or per portion
+type Nutrients struct {
+	Kcal      float64 `json:"kcal"`
+	KJ        float64 `json:"kj"`
+	Fat       float64 `json:"fat"`
+	Saturates float64 `json:"saturates"`
+	Carbs     float64 `json:"carbs"`
+	Sugars    float64 `json:"sugars"`
+	Fibre     float64 `json:"fibre"`
+	Protein   float64 `json:"protein"`
+	Salt      float64 `json:"salt"`
+}
+
+// FromMacros returns the nutrients of product macros. Nutrients the macros do not give are
+// 0, so use Macros.Has to tell them apart from those that are 0.
+func FromMacros(m product.Macros) Nutrients {
+	kj := m.KJ()
+	if kj == 0 {
+		kj = m.Kcal() * 4.184
+	}
+	return Nutrients{
+		Kcal:      m.Kcal(),
+		KJ:        kj,
+		Fat:       m.Fat(),
+		Saturates: m.Saturates(),
+		Carbs:     m.Carbs(),
+		Sugars:    m.Sugars(),
+		Fibre:     m.Fibre(),
+		Protein:   m.Protein(),
+		Salt:      m.Salt(),
+	}
+}
+
+// Add returns n plus other multiplied by factor, e.g. to total several packs or servings
+func (n Nutrients) Add(other Nutrients, factor float64) Nutrients {
+	return Nutrients{
+		Kcal:      n.Kcal + other.Kcal*factor,
+		KJ:        n.KJ + other.KJ*factor,
+		Fat:       n.Fat + other.Fat*factor,
+		Saturates: n.Saturates + other.Saturates*factor,
+		Carbs:     n.Carbs + other.Carbs*factor,
+		Sugars:    n.Sugars + other.Sugars*factor,
+		Fibre:     n.Fibre + other.Fibre*factor,
+		Protein:   n.Protein + other.Protein*factor,
+		Salt:      n.Salt + other.Salt*factor,
+	}
+}
+
+// Light is a front-of-pack traffic light colour
+type Light string
+
+const (
+	Green Light = "green"
+	Amber Light = "amber"
+	Red   Light = "red"
+)
+
+// TrafficLights are the colours of the four front-of-pack nutrients, empty when unknown
+type TrafficLights struct {
+	Fat       Light `json:"fat,omitempty"`
+	Saturates Light `json:"saturates,omitempty"`
+	Sugars    Light `json:"sugars,omitempty"`
+	Salt      Light `json:"salt,omitempty"`
+}
+
+// known returns l with the lights of nutrients m does not give cleared, as they are
+// unknown rather than green
+func (l TrafficLights) known(m product.Macros) TrafficLights {
+	clear := func(light *Light, n product.Nutrient) {
+		if !m.Has(n) {
+			*light = ""
+		}
+	}
+	clear(&l.Fat, product.Fat)
+	clear(&l.Saturates, product.Saturates)
+	clear(&l.Sugars, product.Sugars)
+	clear(&l.Salt, product.Salt)
+	return l
+}
+
+// criteria are the upper bounds for green and amber per 100g or 100ml, and the per portion
+// amount above which a portion larger than 100g or 100ml is red
+type criteria struct{ green, amber, portion float64 }
+
+// from the Department of Health guide to creating a front of pack nutrition label, 2016
+var (
+	food = map[string]criteria{
+		"fat":       {3, 17.5, 21},
+		"saturates": {1.5, 5, 6},
+		"sugars":    {5, 22.5, 27},
+		"salt":      {0.3, 1.5, 1.8},
+	}
+	drink = map[string]criteria{
+		"fat":       {1.5, 8.75, 10.5},
+		"saturates": {0.75, 2.5, 3},
+		"sugars":    {2.5, 11.25, 13.5},
+		"salt":      {0.3, 0.75, 0.9},
+	}
+)
+
+func light(c criteria, per100 float64) Light {
+	switch {
+	case per100 <= c.green:
+		return Green
+	case per100 <= c.amber:
+		return Amber
+	}
+	return Red
+}
+
+// Lights returns the traffic lights for nutrients per 100g, or per 100ml for drinks
+func Lights(per100 Nutrients, isDrink bool) TrafficLights {
+	c := food
+	if isDrink {
+		c = drink
+	}
+	return TrafficLights{
+		Fat:       light(c["fat"], per100.Fat),
+		Saturates: light(c["saturates"], per100.Saturates),
+		Sugars:    light(c["sugars"], per100.Sugars),
+		Salt:      light(c["salt"], per100.Salt),
+	}
+}
+
+// PortionLights returns the traffic lights for a portion of size grams or ml. They are those
+// per 100g, except that portions over 100g are red when they exceed the per portion criteria.
+func PortionLights(per100, portion Nutrients, size float64, isDrink bool) TrafficLights {
+	lights := Lights(per100, isDrink)
+	if size <= 100 {
+		return lights
+	}
+	c := food
+	if isDrink {
+		c = drink
+	}
+	red := func(l *Light, name string, amount float64) {
+		if amount > c[name].portion {
+			*l = Red
+		}
+	}
+	red(&lights.Fat, "fat", portion.Fat)
+	red(&lights.Saturates, "saturates", portion.Saturates)
+	red(&lights.Sugars, "sugars", portion.Sugars)
+	red(&lights.Salt, "salt", portion.Salt)
+	return lights
+}
+
+// points returns the number of thresholds value exceeds
+func points(value float64, thresholds []float64) int {
+	n := 0
+	for _, t := range thresholds {
+		if value > t {
+			n++
+		}
+	}
+	return n
+}
+
+// Nutri-Score thresholds for foods and drinks, from the 2017 Santé publique France specification
+var (
+	energyFood   = []float64{335, 670, 1005, 1340, 1675, 2010, 2345, 2680, 3015, 3350}
+	sugarsFood   = []float64{4.5, 9, 13.5, 18, 22.5, 27, 31, 36, 40, 45}
+	saturatesAll = []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	sodiumAll    = []float64{90, 180, 270, 360, 450, 540, 630, 720, 810, 900}
+	fibreAll     = []float64{0.9, 1.9, 2.8, 3.7, 4.7}
+	proteinAll   = []float64{1.6, 3.2, 4.8, 6.4, 8}
+	energyDrink  = []float64{0, 30, 60, 90, 120, 150, 180, 210, 240, 270}
+	sugarsDrink  = []float64{0, 1.5, 3, 4.5, 6, 7.5, 9, 10.5, 12, 13.5}
+	gradesFood   = []int{-1, 2, 10, 18}
+	// A is reserved for water
+	gradesDrink   = []int{-99, 1, 5, 9}
+	gradeLetters  = []string{"A", "B", "C", "D", "E"}
+	fruitVegWords = regexp.MustCompile(`(?i)\b(fruit|vegetables?)\b`)
+)
+
+// fruitVegPoints scores the percentage of fruit, vegetables, pulses and nuts
+func fruitVegPoints(percent float64, isDrink bool) int {
+	switch {
+	case percent > 80 && isDrink:
+		return 10
+	case percent > 80:
+		return 5
+	case percent > 60 && isDrink:
+		return 4
+	case percent > 60:
+		return 2
+	case percent > 40 && isDrink:
+		return 2
+	case percent > 40:
+		return 1
+	}
+	return 0
+}
+
+// NutriScore returns the Nutri-Score points and grade A to E for nutrients per 100g, or
+// per 100ml for drinks, containing fruitVeg percent fruit, vegetables, pulses and nuts.
+// Lower points are healthier. Cheeses, fats and waters, which have their own rules, are
+// scored as other foods and drinks.
+func NutriScore(per100 Nutrients, fruitVeg float64, isDrink bool) (int, string) {
+	energy, sugars, grades := energyFood, sugarsFood, gradesFood
+	if isDrink {
+		energy, sugars, grades = energyDrink, sugarsDrink, gradesDrink
+	}
+	// salt is 2.5 times the sodium
+	negative := points(per100.KJ, energy) + points(per100.Sugars, sugars) +
+		points(per100.Saturates, saturatesAll) + points(per100.Salt*400, sodiumAll)
+	fruitVegScore := fruitVegPoints(fruitVeg, isDrink)
+	score := negative - points(per100.Fibre, fibreAll) - fruitVegScore
+	// protein only counts for less unhealthy products or those mostly fruit and vegetables
+	if negative < 11 || fruitVegScore >= 5 {
+		score -= points(per100.Protein, proteinAll)
+	}
+
+	grade := len(grades)
+	for i, bound := range grades {
+		if score <= bound {
+			grade = i
+			break
+		}
+	}
+	return score, gradeLetters[grade]
+}
+
+// Health is the traffic lights and Nutri-Score of a product. The score and grade are left
+// out when a nutrient they need is unknown.
+type Health struct {
+	Drink      bool           `json:"drink"`
+	Per100     TrafficLights  `json:"per100"`
+	PerPortion *TrafficLights `json:"perPortion,omitempty"`
+	NutriScore *int           `json:"nutriScore,omitempty"`
+	Grade      string         `json:"grade,omitempty"`
+}
+
+// scored are the nutrients the Nutri-Score needs. Fibre and protein are often left off UK
+// labels, and when missing only lose the points they would take off.
+const scored = product.Energy | product.Saturates | product.Sugars | product.Salt
+
+// Assess computes the health of p, and reports false when it has no nutrition per 100g or
+// 100ml. Lights of nutrients p does not give are left empty.
+func Assess(p *product.Product) (Health, bool) {
+	comp := p.PerComp()
+	if comp.Size() != 100 || !comp.HasAny() {
+		return Health{}, false
+	}
+	isDrink := comp.IsLiquid()
+	nutrients := FromMacros(comp)
+
+	health := Health{Drink: isDrink, Per100: Lights(nutrients, isDrink).known(comp)}
+	if serving := p.PerServing(); serving.Size() > 0 {
+		lights := PortionLights(nutrients, FromMacros(serving), serving.Size(), isDrink).known(comp)
+		health.PerPortion = &lights
+	}
+	if comp.Has(scored) {
+		score, grade := NutriScore(nutrients, FruitVeg(p), isDrink)
+		health.NutriScore, health.Grade = &score, grade
+	}
+	return health, true
+}
+
+// FruitVeg estimates the percentage of fruit, vegetables, pulses and nuts in p, which
+// Tesco does not give. Products in fruit and vegetable departments count as 100%,
+// otherwise 0.
+func FruitVeg(p *product.Product) float64 {
+	for _, category := range p.Categories()[:2] {
+		if fruitVegWords.MatchString(category) {
+			return 100
+		}
+	}
+	return 0
+}
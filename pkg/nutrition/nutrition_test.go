@@ -0,0 +1,173 @@
+package nutrition
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestLights(t *testing.T) {
+	per100 := Nutrients{Fat: 10, Saturates: 4.2, Sugars: 0, Salt: 1.6}
+	want := TrafficLights{Fat: Amber, Saturates: Amber, Sugars: Green, Salt: Red}
+	if got := Lights(per100, false); got != want {
+		t.Errorf("Lights() got = %+v, want %+v", got, want)
+	}
+
+	drink := Nutrients{Fat: 1, Saturates: 0.7, Sugars: 10.6, Salt: 0}
+	want = TrafficLights{Fat: Green, Saturates: Green, Sugars: Amber, Salt: Green}
+	if got := Lights(drink, true); got != want {
+		t.Errorf("Lights() for a drink got = %+v, want %+v", got, want)
+	}
+}
+
+func TestPortionLights(t *testing.T) {
+	per100 := Nutrients{Fat: 10, Saturates: 4.2, Salt: 0.2}
+	portion := Nutrients{Fat: 25.5, Saturates: 10.7, Salt: 0.4}
+	want := TrafficLights{Fat: Red, Saturates: Red, Sugars: Green, Salt: Green}
+	if got := PortionLights(per100, portion, 255, false); got != want {
+		t.Errorf("PortionLights() got = %+v, want %+v", got, want)
+	}
+	// portions of 100g or less use the per 100g criteria
+	small := Nutrients{Fat: 25, Saturates: 8}
+	want = TrafficLights{Fat: Amber, Saturates: Amber, Sugars: Green, Salt: Green}
+	if got := PortionLights(per100, small, 80, false); got != want {
+		t.Errorf("PortionLights() for a small portion got = %+v, want %+v", got, want)
+	}
+}
+
+func TestNutriScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		per100    Nutrients
+		fruitVeg  float64
+		drink     bool
+		wantScore int
+		wantGrade string
+	}{
+		{"rump steak", Nutrients{KJ: 715, Saturates: 4.2, Protein: 20.3, Salt: 0.2}, 0, false, 1, "B"},
+		{"bananas", Nutrients{KJ: 400, Sugars: 20, Fibre: 1.1, Protein: 1.2}, 100, false, -1, "A"},
+		{"chocolate", Nutrients{KJ: 2250, Sugars: 56, Saturates: 18, Fibre: 2, Protein: 7, Salt: 0.2}, 0, false, 24, "E"},
+		{"cola", Nutrients{KJ: 180, Sugars: 10.6}, 0, true, 14, "E"},
+		{"diet cola", Nutrients{KJ: 1.7}, 0, true, 1, "B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, grade := NutriScore(tt.per100, tt.fruitVeg, tt.drink)
+			if score != tt.wantScore || grade != tt.wantGrade {
+				t.Errorf("NutriScore() got = %v %v, want %v %v", score, grade, tt.wantScore, tt.wantGrade)
+			}
+		})
+	}
+}
+
+func TestAssess(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		wantOK    bool
+		wantLight TrafficLights
+		wantGrade string
+		// wantPortionFat is empty when there are no per portion lights
+		wantPortionFat Light
+	}{
+		{
+			"rump steak",
+			`{"product":{"details":{"nutritionInfo":[
+				{"name":"Typical Values","perComp":"Per 100g","perServing":"One steak (255g)"},
+				{"name":"Energy","perComp":"715kJ / 171kcal","perServing":"1824kJ / 437kcal"},
+				{"name":"Fat","perComp":"10.0g","perServing":"25.5g"},
+				{"name":"Saturates","perComp":"4.2g","perServing":"10.7g"},
+				{"name":"Sugars","perComp":"0g","perServing":"0g"},
+				{"name":"Protein","perComp":"20.3g","perServing":"51.8g"},
+				{"name":"Salt","perComp":"0.2g","perServing":"0.4g"}]}}}`,
+			true,
+			TrafficLights{Fat: Amber, Saturates: Amber, Sugars: Green, Salt: Green},
+			"B",
+			Red,
+		},
+		{
+			"no sugars given",
+			`{"product":{"details":{"nutritionInfo":[
+				{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},
+				{"name":"Energy","perComp":"715kJ / 171kcal","perServing":"-"},
+				{"name":"Fat","perComp":"10.0g","perServing":"-"},
+				{"name":"Saturates","perComp":"4.2g","perServing":"-"},
+				{"name":"Salt","perComp":"0.2g","perServing":"-"}]}}}`,
+			true,
+			TrafficLights{Fat: Amber, Saturates: Amber, Salt: Green},
+			"",
+			"",
+		},
+		{"no nutrition", `{"product":{}}`, false, TrafficLights{}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := product.NewProduct(tt.raw, product.IDToURL("300400483"))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			health, ok := Assess(p)
+			if ok != tt.wantOK {
+				t.Fatalf("Assess() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if health.Per100 != tt.wantLight || health.Grade != tt.wantGrade || (health.NutriScore == nil) != (tt.wantGrade == "") {
+				t.Errorf("Assess() got = %+v, want lights %+v grade %q", health, tt.wantLight, tt.wantGrade)
+			}
+			if portion := health.PerPortion; (portion == nil) != (tt.wantPortionFat == "") || (portion != nil && portion.Fat != tt.wantPortionFat) {
+				t.Errorf("Assess() per portion = %+v, want fat %q", portion, tt.wantPortionFat)
+			}
+		})
+	}
+}
+
+func TestTotal(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		raw  string
+		// want is the pack size, servings, protein and kcal per pack and serving, and cost per serving
+		want string
+	}{
+		{
+			"one serving", "300400483",
+			`{"product":{"price":3.55,"details":{"packSize":[{"value":"255","units":"g"}],` +
+				`"numberOfUses":"This pack contains 1 serving","nutritionInfo":[` +
+				`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},` +
+				`{"name":"Energy","perComp":"715kJ / 171kcal","perServing":"-"},` +
+				`{"name":"Protein","perComp":"20.3g","perServing":"-"}]}}}`,
+			"255g 1 servings, pack 51.765g 436.05kcal, serving 51.765g 436.05kcal, £3.550",
+		},
+		{
+			"several servings", "100000001",
+			`{"pageTitle":"Tesco Chicken Breast 650G","product":{"price":3.9,"details":{` +
+				`"packSize":[{"value":"650","units":"g"}],"numberOfUses":"Typically 4 servings","nutritionInfo":[` +
+				`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},` +
+				`{"name":"Energy","perComp":"440kJ / 104kcal","perServing":"-"},` +
+				`{"name":"Protein","perComp":"24.0g","perServing":"-"}]}}}`,
+			"650g 4 servings, pack 156g 676kcal, serving 39g 169kcal, £0.975",
+		},
+		{"no pack size", "305009745", `{"product":{}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := product.NewProduct(tt.raw, product.IDToURL(tt.id))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			totals, ok := Total(p)
+			got := ""
+			if ok {
+				if totals.PerPack == nil || totals.PerServing == nil || totals.CostPerServing == nil {
+					t.Fatalf("Total() got = %+v", totals)
+				}
+				got = fmt.Sprintf("%vg %v servings, pack %.6gg %.6gkcal, serving %.6gg %.6gkcal, £%.3f",
+					totals.PackSize, totals.Servings, totals.PerPack.Protein, totals.PerPack.Kcal,
+					totals.PerServing.Protein, totals.PerServing.Kcal, *totals.CostPerServing)
+			}
+			if got != tt.want {
+				t.Errorf("Total() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
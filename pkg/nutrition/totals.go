@@ -0,0 +1,35 @@
+package nutrition
+
+import "github.com/mattburman/tesco/pkg/product"
+
+// Totals are the nutrients in a whole pack and in a serving, and the cost of a serving
+type Totals struct {
+	PackSize       float64    `json:"packSize,omitempty"`
+	Unit           string     `json:"unit,omitempty"`
+	Servings       float64    `json:"servings,omitempty"`
+	PerPack        *Nutrients `json:"perPack,omitempty"`
+	PerServing     *Nutrients `json:"perServing,omitempty"`
+	CostPerServing *float64   `json:"costPerServing,omitempty"`
+}
+
+// Total computes the totals of p, and reports false when neither its pack size nor
+// number of servings is known
+func Total(p *product.Product) (Totals, bool) {
+	pack := p.Pack()
+	totals := Totals{PackSize: pack.Size(), Unit: pack.Unit(), Servings: p.Servings()}
+	if totals.PackSize == 0 && totals.Servings == 0 {
+		return Totals{}, false
+	}
+	if perPack, ok := p.PerPack(); ok {
+		nutrients := FromMacros(perPack)
+		totals.PerPack = &nutrients
+	}
+	if serving, ok := p.Serving(); ok {
+		nutrients := FromMacros(serving)
+		totals.PerServing = &nutrients
+	}
+	if cost, ok := p.CostPerServing(); ok {
+		totals.CostPerServing = &cost
+	}
+	return totals, true
+}
@@ -0,0 +1,402 @@
+// Package output writes products in the formats supported by the --output flag
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mattburman/tesco/pkg/ingredients"
+	"github.com/mattburman/tesco/pkg/nutrition"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	JSON        = "json"
+	CompactJSON = "json-compact"
+	YAML        = "yaml"
+	Table       = "table"
+	CSV         = "csv"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{JSON, CompactJSON, YAML, Table, CSV}
+
+// Row is the flattened summary of a product used by the table and csv formats
+type Row struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Price          float64 `json:"price"`
+	UnitPrice      float64 `json:"unitPrice"`
+	UnitOfMeasure  string  `json:"unitOfMeasure"`
+	Per            string  `json:"per"`
+	Kcal           float64 `json:"kcal"`
+	Protein        float64 `json:"protein"`
+	Carbs          float64 `json:"carbs"`
+	Fat            float64 `json:"fat"`
+	Serving        string  `json:"serving"`
+	ServingKcal    float64 `json:"servingKcal"`
+	ServingProtein float64 `json:"servingProtein"`
+	ServingCarbs   float64 `json:"servingCarbs"`
+	ServingFat     float64 `json:"servingFat"`
+	// Score is a computed value, such as a ranking metric, labelled by Document.Score
+	Score *float64 `json:"score,omitempty"`
+	// Snippet is an excerpt of the text that matched a search
+	Snippet string `json:"snippet,omitempty"`
+	// Offer describes a promotion on the product
+	Offer string `json:"offer,omitempty"`
+}
+
+// Document is data to be written. JSON is used by the json and yaml formats, falling back
+// to Rows when empty. Rows are used by the table and csv formats.
+type Document struct {
+	JSON string
+	Rows []Row
+	// Score labels the Score column of the rows, which is left out when empty
+	Score string
+	// Ingredients of a single product are added to JSON and YAML as parsedIngredients
+	// and listed below the table
+	Ingredients []ingredients.Ingredient
+	// Health of a single product is added to JSON and YAML as health and shown below the table
+	Health *nutrition.Health
+	// Totals of a single product are added to JSON and YAML as totals and shown below the table
+	Totals *nutrition.Totals
+}
+
+func (doc Document) hasSnippets() bool {
+	for _, r := range doc.Rows {
+		if r.Snippet != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (doc Document) hasOffers() bool {
+	for _, r := range doc.Rows {
+		if r.Offer != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if format is not supported
+func Validate(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format %q, must be one of: %v", format, strings.Join(Formats, ", "))
+}
+
+// ProductRow flattens a parsed product into a Row
+func ProductRow(p *product.Product) Row {
+	perComp, perServing := p.PerComp(), p.PerServing()
+	return Row{
+		ID:             p.ID(),
+		Name:           p.Name(),
+		Price:          p.Price(),
+		UnitPrice:      p.UnitPrice(),
+		UnitOfMeasure:  p.UnitOfMeasure(),
+		Per:            perComp.Per(),
+		Kcal:           perComp.Kcal(),
+		Protein:        perComp.Protein(),
+		Carbs:          perComp.Carbs(),
+		Fat:            perComp.Fat(),
+		Serving:        perServing.Per(),
+		ServingKcal:    perServing.Kcal(),
+		ServingProtein: perServing.Protein(),
+		ServingCarbs:   perServing.Carbs(),
+		ServingFat:     perServing.Fat(),
+	}
+}
+
+// ListingRows returns rows for the products at path in a listing such as a category page.
+// Listings carry no nutrition information so only the ID, name and prices are set.
+func ListingRows(listing string, path string) []Row {
+	products := gjson.Get(listing, path).Array()
+	rows := make([]Row, len(products))
+	for i, p := range products {
+		rows[i] = Row{
+			ID:            p.Get("id").String(),
+			Name:          p.Get("title").String(),
+			Price:         p.Get("price").Float(),
+			UnitPrice:     p.Get("unitPrice").Float(),
+			UnitOfMeasure: p.Get("unitOfMeasure").String(),
+		}
+	}
+	return rows
+}
+
+// Write writes doc to w in format
+func Write(w io.Writer, format string, doc Document) error {
+	switch format {
+	case JSON, CompactJSON, YAML:
+		raw := doc.JSON
+		if raw == "" {
+			b, err := json.Marshal(doc.Rows)
+			if err != nil {
+				return fmt.Errorf("unable to marshal rows: %v", err)
+			}
+			raw = string(b)
+		}
+		var err error
+		if doc.Health != nil {
+			if raw, err = withField(raw, "health", doc.Health); err != nil {
+				return err
+			}
+		}
+		if doc.Totals != nil {
+			if raw, err = withField(raw, "totals", doc.Totals); err != nil {
+				return err
+			}
+		}
+		if len(doc.Ingredients) > 0 {
+			if raw, err = withField(raw, "parsedIngredients", doc.Ingredients); err != nil {
+				return err
+			}
+		}
+		return writeJSON(w, format, raw)
+	case Table:
+		return writeTable(w, doc)
+	case CSV:
+		return writeCSV(w, doc)
+	}
+	return Validate(format)
+}
+
+// withField adds a field to the start of a JSON object, leaving other JSON unchanged
+func withField(raw string, key string, value interface{}) (string, error) {
+	if !gjson.Parse(raw).IsObject() {
+		return raw, nil
+	}
+	b, err := json.Marshal(map[string]interface{}{key: value})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal %v: %v", key, err)
+	}
+	rest := strings.TrimSpace(raw)[1:]
+	if strings.TrimSpace(rest) == "}" {
+		return string(b), nil
+	}
+	return strings.TrimSuffix(string(b), "}") + "," + rest, nil
+}
+
+func writeJSON(w io.Writer, format string, raw string) error {
+	if !gjson.Valid(raw) {
+		return fmt.Errorf("invalid json")
+	}
+	switch format {
+	case JSON:
+		_, err := fmt.Fprint(w, gjson.Get(raw, "@pretty").String())
+		return err
+	case CompactJSON:
+		_, err := fmt.Fprintln(w, gjson.Get(raw, "@ugly").String())
+		return err
+	}
+
+	// json is valid yaml, and decoding to a MapSlice keeps the key order
+	var v interface{} = &yaml.MapSlice{}
+	if gjson.Parse(raw).IsArray() {
+		v = &[]yaml.MapSlice{}
+	}
+	if err := yaml.Unmarshal([]byte(raw), v); err != nil {
+		return fmt.Errorf("unable to convert json to yaml: %v", err)
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to marshal yaml: %v", err)
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+var header = []string{
+	"id", "name", "price", "unit_price", "unit_of_measure",
+	"per", "kcal", "protein", "carbs", "fat",
+	"serving", "serving_kcal", "serving_protein", "serving_carbs", "serving_fat",
+}
+
+func (r Row) values() []string {
+	return []string{
+		r.ID, r.Name, formatFloat(r.Price), formatFloat(r.UnitPrice), r.UnitOfMeasure,
+		r.Per, formatFloat(r.Kcal), formatFloat(r.Protein), formatFloat(r.Carbs), formatFloat(r.Fat),
+		r.Serving, formatFloat(r.ServingKcal), formatFloat(r.ServingProtein), formatFloat(r.ServingCarbs), formatFloat(r.ServingFat),
+	}
+}
+
+func writeTable(w io.Writer, doc Document) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if doc.Score != "" {
+		fmt.Fprintf(tw, "%v\t", strings.ToUpper(doc.Score))
+	}
+	fmt.Fprint(tw, "NAME\tPRICE\tPER\tKCAL\tPROTEIN\tCARBS\tFAT\tSERVING\tKCAL/SRV\tPROTEIN/SRV\tCARBS/SRV\tFAT/SRV")
+	offers, snippets := doc.hasOffers(), doc.hasSnippets()
+	if offers {
+		fmt.Fprint(tw, "\tOFFER")
+	}
+	if snippets {
+		fmt.Fprint(tw, "\tSNIPPET")
+	}
+	fmt.Fprintln(tw)
+	for _, r := range doc.Rows {
+		if doc.Score != "" {
+			fmt.Fprintf(tw, "%v\t", formatScore(r.Score))
+		}
+		fmt.Fprintf(tw, "%v\t£%.2f\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v",
+			r.Name, r.Price,
+			dash(r.Per), formatFloat(r.Kcal), formatFloat(r.Protein), formatFloat(r.Carbs), formatFloat(r.Fat),
+			dash(r.Serving), formatFloat(r.ServingKcal), formatFloat(r.ServingProtein), formatFloat(r.ServingCarbs), formatFloat(r.ServingFat),
+		)
+		if offers {
+			fmt.Fprintf(tw, "\t%v", dash(r.Offer))
+		}
+		if snippets {
+			fmt.Fprintf(tw, "\t%v", strings.Join(strings.Fields(r.Snippet), " "))
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if doc.Health != nil {
+		writeHealth(w, *doc.Health)
+	}
+	if doc.Totals != nil {
+		writeTotals(w, *doc.Totals)
+	}
+	if len(doc.Ingredients) > 0 {
+		fmt.Fprintln(w, "\nINGREDIENTS (allergens in upper case)")
+		writeIngredients(w, doc.Ingredients, "  ")
+	}
+	return nil
+}
+
+func writeHealth(w io.Writer, health nutrition.Health) {
+	per := "100g"
+	if health.Drink {
+		per = "100ml"
+	}
+	if health.NutriScore != nil {
+		fmt.Fprintf(w, "\nNUTRI-SCORE %v (score %v)\n", health.Grade, *health.NutriScore)
+	} else {
+		fmt.Fprintln(w, "\nNUTRI-SCORE unknown")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TRAFFIC LIGHTS\tFAT\tSATURATES\tSUGARS\tSALT")
+	light := func(l nutrition.Light) string {
+		if l == "" {
+			return "-"
+		}
+		return string(l)
+	}
+	lights := func(label string, l nutrition.TrafficLights) {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", label, light(l.Fat), light(l.Saturates), light(l.Sugars), light(l.Salt))
+	}
+	lights("per "+per, health.Per100)
+	if health.PerPortion != nil {
+		lights("per portion", *health.PerPortion)
+	}
+	tw.Flush()
+}
+
+func writeTotals(w io.Writer, totals nutrition.Totals) {
+	summary := []string{}
+	if totals.PackSize > 0 {
+		summary = append(summary, formatFloat(totals.PackSize)+totals.Unit)
+	}
+	if totals.Servings > 0 {
+		noun := "servings"
+		if totals.Servings == 1 {
+			noun = "serving"
+		}
+		summary = append(summary, formatFloat(math.Round(totals.Servings*10)/10)+" "+noun)
+	}
+	if totals.CostPerServing != nil {
+		summary = append(summary, fmt.Sprintf("£%.2f per serving", *totals.CostPerServing))
+	}
+	fmt.Fprintf(w, "\nPACK %v\n", strings.Join(summary, ", "))
+	if totals.PerPack == nil && totals.PerServing == nil {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOTALS\tKCAL\tPROTEIN\tCARBS\tFAT\tSALT")
+	nutrients := func(label string, n *nutrition.Nutrients) {
+		if n != nil {
+			fmt.Fprintf(tw, "%v\t%.0f\t%.1f\t%.1f\t%.1f\t%.2f\n", label, n.Kcal, n.Protein, n.Carbs, n.Fat, n.Salt)
+		}
+	}
+	nutrients("per pack", totals.PerPack)
+	nutrients("per serving", totals.PerServing)
+	tw.Flush()
+}
+
+func writeIngredients(w io.Writer, list []ingredients.Ingredient, indent string) {
+	for _, ingredient := range list {
+		// sub-ingredients are listed beneath rather than inline
+		line := ingredients.Ingredient{Name: ingredient.Name, Percent: ingredient.Percent, Allergens: ingredient.Allergens}
+		fmt.Fprintf(w, "%v%v\n", indent, line)
+		writeIngredients(w, ingredient.Ingredients, indent+"  ")
+	}
+}
+
+func writeCSV(w io.Writer, doc Document) error {
+	cw := csv.NewWriter(w)
+	h := header
+	if doc.Score != "" {
+		h = append([]string{doc.Score}, h...)
+	}
+	offers, snippets := doc.hasOffers(), doc.hasSnippets()
+	if offers {
+		h = append(h, "offer")
+	}
+	if snippets {
+		h = append(h, "snippet")
+	}
+	if err := cw.Write(h); err != nil {
+		return err
+	}
+	for _, r := range doc.Rows {
+		values := r.values()
+		if doc.Score != "" {
+			values = append([]string{formatScore(r.Score)}, values...)
+		}
+		if offers {
+			values = append(values, r.Offer)
+		}
+		if snippets {
+			values = append(values, r.Snippet)
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatScore(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', 4, 64)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,164 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mattburman/tesco/pkg/ingredients"
+	"github.com/mattburman/tesco/pkg/nutrition"
+)
+
+func TestWrite(t *testing.T) {
+	doc := Document{
+		JSON: `{"title":"Tesco Rump Steak 255G","price":3.55,"tags":["beef"]}`,
+		Rows: []Row{{ID: "300400483", Name: "Tesco Rump Steak 255G", Price: 3.55, Per: "Per 100g", Protein: 20.3}},
+	}
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			CompactJSON,
+			"{\"title\":\"Tesco Rump Steak 255G\",\"price\":3.55,\"tags\":[\"beef\"]}\n",
+			false,
+		},
+		{
+			YAML,
+			"title: Tesco Rump Steak 255G\nprice: 3.55\ntags:\n- beef\n",
+			false,
+		},
+		{
+			CSV,
+			"id,name,price,unit_price,unit_of_measure,per,kcal,protein,carbs,fat,serving,serving_kcal,serving_protein,serving_carbs,serving_fat\n" +
+				"300400483,Tesco Rump Steak 255G,3.55,0,,Per 100g,0,20.3,0,0,,0,0,0,0\n",
+			false,
+		},
+		{
+			"xml",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Write(&buf, tt.format, doc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Write() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Write() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRowsAsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, CompactJSON, Document{Rows: []Row{{ID: "1", Name: "Milk"}}})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := `[{"id":"1","name":"Milk",`
+	if got := buf.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("Write() got = %q, want prefix %q", got, want)
+	}
+}
+
+func TestWriteIngredients(t *testing.T) {
+	doc := Document{
+		JSON:        `{"title":"Sausages"}`,
+		Rows:        []Row{{ID: "1", Name: "Sausages"}},
+		Ingredients: ingredients.Parse([]string{"Pork (80%), Rusk (<b>Wheat</b> Flour, Salt)"}),
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, CompactJSON, doc); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := `{"parsedIngredients":[{"name":"Pork","percent":80},{"name":"Rusk","ingredients":[{"name":"Wheat Flour","allergens":["wheat"]},{"name":"Salt"}]}],"title":"Sausages"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Write() got = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, Table, doc); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want = "INGREDIENTS (allergens in upper case)\n  Pork (80%)\n  Rusk\n    WHEAT Flour\n    Salt\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("Write() got = %q, want suffix %q", got, want)
+	}
+}
+
+func TestWriteHealth(t *testing.T) {
+	score := 1
+	doc := Document{
+		Rows: []Row{{ID: "1", Name: "Rump Steak"}},
+		Health: &nutrition.Health{
+			Per100:     nutrition.TrafficLights{Fat: nutrition.Amber, Saturates: nutrition.Amber, Sugars: nutrition.Green, Salt: nutrition.Green},
+			PerPortion: &nutrition.TrafficLights{Fat: nutrition.Red, Saturates: nutrition.Red, Sugars: nutrition.Green, Salt: nutrition.Green},
+			NutriScore: &score,
+			Grade:      "B",
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Table, doc); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "NUTRI-SCORE B (score 1)\n" +
+		"TRAFFIC LIGHTS  FAT    SATURATES  SUGARS  SALT\n" +
+		"per 100g        amber  amber      green   green\n" +
+		"per portion     red    red        green   green\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("Write() got = %q, want suffix %q", got, want)
+	}
+}
+
+func TestWriteTotals(t *testing.T) {
+	cost := 0.975
+	doc := Document{
+		Rows: []Row{{ID: "1", Name: "Chicken Breast"}},
+		Totals: &nutrition.Totals{
+			PackSize:       650,
+			Unit:           "g",
+			Servings:       4,
+			PerPack:        &nutrition.Nutrients{Kcal: 676, Protein: 156, Fat: 9.75, Salt: 0.65},
+			PerServing:     &nutrition.Nutrients{Kcal: 169, Protein: 39, Fat: 2.4375, Salt: 0.1625},
+			CostPerServing: &cost,
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Table, doc); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "PACK 650g, 4 servings, £0.97 per serving\n" +
+		"TOTALS       KCAL  PROTEIN  CARBS  FAT  SALT\n" +
+		"per pack     676   156.0    0.0    9.8  0.65\n" +
+		"per serving  169   39.0     0.0    2.4  0.16\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("Write() got = %q, want suffix %q", got, want)
+	}
+}
+
+func TestWriteOffers(t *testing.T) {
+	price := 1.5
+	doc := Document{
+		Rows: []Row{
+			{ID: "1", Name: "Peppercorn Sauce", Price: 2, Score: &price, Offer: "Any 2 for £3.00 (multibuy)"},
+			{ID: "2", Name: "Mushrooms", Price: 1},
+		},
+		Score: "deal price",
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, CSV, doc); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasSuffix(lines[0], ",offer") || !strings.HasPrefix(lines[1], "1.5000,1,") || !strings.HasSuffix(lines[1], ",Any 2 for £3.00 (multibuy)") || !strings.HasSuffix(lines[2], ",") {
+		t.Errorf("Write() got = %q", buf.String())
+	}
+}
@@ -0,0 +1,246 @@
+// Package ownlabel pairs Tesco's own-label products with branded equivalents on the same
+// shelf and compares their prices and nutrition
+package ownlabel
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattburman/tesco/pkg/nutrition"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/rank"
+)
+
+// DefaultBrands are Tesco's own-label brands. A brand also counts as own-label when it
+// starts with one of them, e.g. "TESCO FINEST".
+var DefaultBrands = []string{
+	"TESCO", "STOCKWELL & CO", "GROWERS HARVEST", "HEARTY FOOD CO", "EASTMANS", "WOODS",
+	"BOSWELL FARMS", "ROSEDENE FARMS", "REDMERE FARMS", "WILLOW FARMS", "NIGHTINGALE FARMS",
+}
+
+// DefaultMinSimilarity is the share of name words two products must have in common to
+// be paired
+const DefaultMinSimilarity = 0.5
+
+// Options control how products are paired
+type Options struct {
+	// Brands are the own-label brands, DefaultBrands when empty
+	Brands []string
+	// MinSimilarity is the least name similarity, from 0 to 1, of a pair
+	MinSimilarity float64
+}
+
+// Side is one product of a pair
+type Side struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Brand string  `json:"brand"`
+	Price float64 `json:"price"`
+	// UnitPrice is the price per Unit, e.g. 100g, 100ml or each
+	UnitPrice float64 `json:"unitPrice"`
+	// Per100 is nil when the nutrition per 100g or 100ml is unknown
+	Per100 *nutrition.Nutrients `json:"per100"`
+}
+
+// Pair is an own-label product and its nearest branded equivalent on the same shelf
+type Pair struct {
+	Aisle      string  `json:"aisle"`
+	Shelf      string  `json:"shelf"`
+	Unit       string  `json:"unit"`
+	OwnLabel   Side    `json:"ownLabel"`
+	Branded    Side    `json:"branded"`
+	Similarity float64 `json:"similarity"`
+	// Saving is the fraction of the branded unit price saved by buying own-label, negative
+	// when own-label costs more
+	Saving float64 `json:"saving"`
+}
+
+// Summary totals the pairs in an aisle
+type Summary struct {
+	Aisle string `json:"aisle"`
+	Pairs int    `json:"pairs"`
+	// Cheaper and Dearer count the pairs where own-label costs less or more per unit
+	Cheaper int `json:"cheaper"`
+	Dearer  int `json:"dearer"`
+	// AverageSaving is the mean Saving of the pairs
+	AverageSaving float64 `json:"averageSaving"`
+}
+
+// Report is every pair found, largest saving first, and a summary of each aisle
+type Report struct {
+	Pairs  []Pair    `json:"pairs"`
+	Aisles []Summary `json:"aisles"`
+}
+
+// IsOwnLabel reports whether brand is one of brands, or starts with one of them
+func IsOwnLabel(brand string, brands []string) bool {
+	brand = normalize(brand)
+	if brand == "" {
+		return false
+	}
+	for _, own := range brands {
+		own = normalize(own)
+		if own != "" && (brand == own || strings.HasPrefix(brand, own+" ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare pairs each own-label product with the branded product on the same shelf whose
+// name is most similar, once brands and pack sizes are left out. Products are only paired
+// when their unit prices are in comparable units.
+func Compare(products []*product.Product, opts Options) Report {
+	brands := opts.Brands
+	if len(brands) == 0 {
+		brands = DefaultBrands
+	}
+	type candidate struct {
+		p     *product.Product
+		words map[string]bool
+		unit  string
+		price float64
+	}
+	own := map[string][]candidate{}
+	branded := map[string][]candidate{}
+	shelves := []string{}
+	for _, p := range products {
+		unit, price, ok := unitPrice(p)
+		if !ok || p.Shelf() == "" || p.Brand() == "" {
+			continue
+		}
+		key := strings.ToLower(p.Aisle() + "\x00" + p.Shelf())
+		if len(own[key]) == 0 && len(branded[key]) == 0 {
+			shelves = append(shelves, key)
+		}
+		c := candidate{p: p, words: nameWords(p), unit: unit, price: price}
+		if IsOwnLabel(p.Brand(), brands) {
+			own[key] = append(own[key], c)
+		} else {
+			branded[key] = append(branded[key], c)
+		}
+	}
+
+	report := Report{Pairs: []Pair{}, Aisles: []Summary{}}
+	for _, key := range shelves {
+		for _, o := range own[key] {
+			var best *candidate
+			bestSimilarity := 0.0
+			for i, b := range branded[key] {
+				if b.unit != o.unit {
+					continue
+				}
+				if s := similarity(o.words, b.words); s >= opts.MinSimilarity && s > bestSimilarity {
+					best, bestSimilarity = &branded[key][i], s
+				}
+			}
+			if best == nil {
+				continue
+			}
+			report.Pairs = append(report.Pairs, Pair{
+				Aisle:      o.p.Aisle(),
+				Shelf:      o.p.Shelf(),
+				Unit:       o.unit,
+				OwnLabel:   side(o.p, o.price),
+				Branded:    side(best.p, best.price),
+				Similarity: bestSimilarity,
+				Saving:     (best.price - o.price) / best.price,
+			})
+		}
+	}
+	sort.SliceStable(report.Pairs, func(i, j int) bool { return report.Pairs[i].Saving > report.Pairs[j].Saving })
+
+	aisles := map[string]int{}
+	for _, pair := range report.Pairs {
+		i, ok := aisles[strings.ToLower(pair.Aisle)]
+		if !ok {
+			i = len(report.Aisles)
+			aisles[strings.ToLower(pair.Aisle)] = i
+			report.Aisles = append(report.Aisles, Summary{Aisle: pair.Aisle})
+		}
+		s := &report.Aisles[i]
+		s.Pairs++
+		s.AverageSaving += pair.Saving
+		switch {
+		case pair.Saving > 0:
+			s.Cheaper++
+		case pair.Saving < 0:
+			s.Dearer++
+		}
+	}
+	for i := range report.Aisles {
+		report.Aisles[i].AverageSaving /= float64(report.Aisles[i].Pairs)
+	}
+	sort.SliceStable(report.Aisles, func(i, j int) bool { return report.Aisles[i].AverageSaving > report.Aisles[j].AverageSaving })
+	return report
+}
+
+// unitPrice returns the unit and price per unit of p, per 100g or 100ml where possible
+func unitPrice(p *product.Product) (string, float64, bool) {
+	if price, ok := rank.PricePer100(p.UnitPrice(), p.UnitOfMeasure()); ok {
+		if rank.IsVolume(p.UnitOfMeasure()) {
+			return "100ml", price, true
+		}
+		return "100g", price, true
+	}
+	if p.UnitPrice() > 0 && p.UnitOfMeasure() != "" {
+		return strings.ToLower(p.UnitOfMeasure()), p.UnitPrice(), true
+	}
+	return "", 0, false
+}
+
+func side(p *product.Product, price float64) Side {
+	s := Side{ID: p.ID(), Name: p.Name(), Brand: p.Brand(), Price: p.Price(), UnitPrice: price}
+	comp := p.PerComp()
+	if scaled, ok := comp.Scale(100); ok && comp.HasAny() {
+		n := nutrition.FromMacros(scaled)
+		s.Per100 = &n
+	}
+	return s
+}
+
+var (
+	wordPattern = regexp.MustCompile(`[a-z0-9]+`)
+	// sizePattern matches pack sizes and counts such as 400g, 4x125g, 6pk and 2
+	sizePattern = regexp.MustCompile(`^[0-9.x]*[0-9][0-9.x]*(g|kg|ml|l|ltr|cl|pk|pack|s)?$`)
+	stopWords   = map[string]bool{"and": true, "with": true, "in": true, "of": true, "the": true, "a": true, "x": true, "pack": true}
+)
+
+func words(s string) []string {
+	return wordPattern.FindAllString(strings.ToLower(strings.ReplaceAll(s, "'", "")), -1)
+}
+
+func normalize(s string) string {
+	return strings.Join(words(s), " ")
+}
+
+// nameWords returns the words of p's name that describe the product rather than its brand
+// or pack size
+func nameWords(p *product.Product) map[string]bool {
+	brand := map[string]bool{}
+	for _, word := range words(p.Brand()) {
+		brand[word] = true
+	}
+	set := map[string]bool{}
+	for _, word := range words(p.Name()) {
+		if !brand[word] && !stopWords[word] && !sizePattern.MatchString(word) {
+			set[word] = true
+		}
+	}
+	return set
+}
+
+// similarity is the Jaccard similarity of two sets of words
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for word := range a {
+		if b[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
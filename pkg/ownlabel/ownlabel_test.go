@@ -0,0 +1,96 @@
+package ownlabel
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestIsOwnLabel(t *testing.T) {
+	tests := []struct {
+		brand string
+		want  bool
+	}{
+		{"TESCO", true},
+		{"TESCO FINEST", true},
+		{"Stockwell & Co.", true},
+		{"TESCOTT", false},
+		{"HEINZ", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsOwnLabel(tt.brand, DefaultBrands); got != tt.want {
+			t.Errorf("IsOwnLabel(%q) = %v, want %v", tt.brand, got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	raws := []struct {
+		id  string
+		raw string
+	}{
+		{"1", `{"aisleName":"Tins","shelfName":"Baked Beans","pageTitle":"Tesco Baked Beans In Tomato Sauce 420G",` +
+			`"product":{"id":"1","brandName":"TESCO","price":0.07,"unitPrice":0.15,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 80kcal","perServing":"-"},` +
+			`{"name":"Protein","perComp":"5g","perServing":"-"}]}}}`},
+		{"2", `{"aisleName":"Tins","shelfName":"Baked Beans","pageTitle":"Heinz Baked Beans In Tomato Sauce 415G",` +
+			`"product":{"id":"2","brandName":"HEINZ","price":1.25,"unitPrice":3,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 78kcal","perServing":"-"},` +
+			`{"name":"Protein","perComp":"4.7g","perServing":"-"}]}}}`},
+		{"3", `{"aisleName":"Tins","shelfName":"Baked Beans","pageTitle":"Heinz Baked Beanz Reduced Sugar 4X415g",` +
+			`"product":{"id":"3","brandName":"HEINZ","price":4.15,"unitPrice":2.5,"unitOfMeasure":"kg"}}`},
+		// a branded product on another shelf is never paired
+		{"4", `{"aisleName":"Tins","shelfName":"Soup","pageTitle":"Heinz Cream Of Tomato Soup 400G",` +
+			`"product":{"id":"4","brandName":"HEINZ","price":0.8,"unitPrice":2,"unitOfMeasure":"kg"}}`},
+		{"5", `{"aisleName":"Tins","shelfName":"Soup","pageTitle":"Tesco Finest Cream Of Tomato Soup 600G",` +
+			`"product":{"id":"5","brandName":"TESCO FINEST","price":1.5,"unitPrice":2.5,"unitOfMeasure":"kg"}}`},
+		// nothing branded is similar enough
+		{"6", `{"aisleName":"Tins","shelfName":"Baked Beans","pageTitle":"Tesco Spaghetti Hoops 410G",` +
+			`"product":{"id":"6","brandName":"TESCO","price":0.41,"unitPrice":1,"unitOfMeasure":"kg"}}`},
+		{"7", `{"aisleName":"Tins","shelfName":"Soup","pageTitle":"Tesco Cream Of Tomato Soup 400G",` +
+			`"product":{"id":"7","brandName":"TESCO","price":0.4,"unitPrice":1,"unitOfMeasure":"kg"}}`},
+	}
+	products := []*product.Product{}
+	for _, r := range raws {
+		p, err := product.NewProduct(r.raw, product.IDToURL(r.id))
+		if err != nil {
+			t.Fatalf("NewProduct() error = %v", err)
+		}
+		products = append(products, p)
+	}
+	got := Compare(products, Options{MinSimilarity: DefaultMinSimilarity})
+
+	tests := []struct {
+		own, branded string
+		saving       float64
+		wantPer100   bool
+	}{
+		{"1", "2", 0.95, true},
+		{"7", "4", 0.5, false},
+		{"5", "4", -0.25, false},
+	}
+	if len(got.Pairs) != len(tests) {
+		t.Fatalf("Compare() pairs = %+v, want %v", got.Pairs, len(tests))
+	}
+	for i, tt := range tests {
+		p := got.Pairs[i]
+		if p.OwnLabel.ID != tt.own || p.Branded.ID != tt.branded || math.Abs(p.Saving-tt.saving) > 1e-9 {
+			t.Errorf("Compare() pair %v = %v vs %v saving %v, want %v vs %v saving %v", i, p.OwnLabel.ID, p.Branded.ID, p.Saving, tt.own, tt.branded, tt.saving)
+		}
+		if (p.Branded.Per100 != nil) != tt.wantPer100 {
+			t.Errorf("Compare() pair %v branded per 100 = %+v, want it %v", i, p.Branded.Per100, tt.wantPer100)
+		}
+	}
+	if p := got.Pairs[0]; p.Unit != "100g" || p.OwnLabel.UnitPrice != 0.015 || p.Similarity != 1 || p.Branded.Per100.Protein != 4.7 {
+		t.Errorf("Compare() pair = %+v", p)
+	}
+
+	if len(got.Aisles) != 1 {
+		t.Fatalf("Compare() aisles = %+v, want 1", got.Aisles)
+	}
+	if s := got.Aisles[0]; s.Aisle != "Tins" || s.Pairs != 3 || s.Cheaper != 2 || s.Dearer != 1 || math.Abs(s.AverageSaving-0.4) > 1e-9 {
+		t.Errorf("Compare() aisle = %+v", s)
+	}
+}
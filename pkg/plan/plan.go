@@ -0,0 +1,227 @@
+// Package plan chooses daily servings of products that meet macro targets at the lowest
+// cost, by integer programming over their per-serving macros and prices
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattburman/tesco/pkg/lp"
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+// Range is an inclusive range. A nil Max is unbounded, so a Max of 0 allows none.
+type Range struct {
+	Min float64  `json:"min"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// ParseRange parses "min:max", "min:" or ":max", or a single number as a minimum
+func ParseRange(s string) (Range, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Range{}, nil
+	}
+	parts := strings.SplitN(s, ":", 2)
+	var r Range
+	var err error
+	if parts[0] != "" {
+		if r.Min, err = strconv.ParseFloat(parts[0], 64); err != nil {
+			return Range{}, fmt.Errorf("invalid minimum in %q: %v", s, err)
+		}
+	}
+	if len(parts) == 2 && parts[1] != "" {
+		max, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid maximum in %q: %v", s, err)
+		}
+		r.Max = &max
+	}
+	if r.Min < 0 || (r.Max != nil && (*r.Max < 0 || *r.Max < r.Min)) {
+		return Range{}, fmt.Errorf("invalid range %q", s)
+	}
+	return r, nil
+}
+
+// String formats r as ParseRange parses it
+func (r Range) String() string {
+	s := strconv.FormatFloat(r.Min, 'f', -1, 64) + ":"
+	if r.Max != nil {
+		s += strconv.FormatFloat(*r.Max, 'f', -1, 64)
+	}
+	return s
+}
+
+// Targets are the daily ranges of energy and macros to hit
+type Targets struct {
+	Kcal    Range `json:"kcal"`
+	Protein Range `json:"protein"`
+	Carbs   Range `json:"carbs"`
+	Fat     Range `json:"fat"`
+}
+
+// Options are the targets and limits of a plan
+type Options struct {
+	Targets
+	// Days is the number of days to plan, each eating the same servings
+	Days int
+	// MaxServings is the most servings of one product a day
+	MaxServings int
+	// Budget is the most to spend on the servings eaten over all Days, ignored when 0. It
+	// limits the plan's ServingCost, so the whole packs of its ShoppingCost may cost more.
+	Budget float64
+	// MaxNodes limits the branch and bound search, returning the best plan found
+	MaxNodes int
+}
+
+// Macros are the energy and macros eaten
+type Macros struct {
+	Kcal    float64 `json:"kcal"`
+	Protein float64 `json:"protein"`
+	Carbs   float64 `json:"carbs"`
+	Fat     float64 `json:"fat"`
+}
+
+// Line is a product in a plan
+type Line struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Serving        string  `json:"serving"`
+	ServingsPerDay int     `json:"servingsPerDay"`
+	Servings       int     `json:"servings"`
+	CostPerServing float64 `json:"costPerServing"`
+	// Packs is the number of packs to buy for the Servings over all days
+	Packs     int     `json:"packs"`
+	PackPrice float64 `json:"packPrice"`
+	Cost      float64 `json:"cost"`
+}
+
+// Plan is the daily servings of products and the shopping list to buy them
+type Plan struct {
+	Days  int    `json:"days"`
+	Lines []Line `json:"lines"`
+	Daily Macros `json:"daily"`
+	// ServingCost is the cost of the servings eaten, and ShoppingCost that of whole packs
+	ServingCost  float64 `json:"servingCost"`
+	ShoppingCost float64 `json:"shoppingCost"`
+	// Optimal is false if the search stopped at its node limit
+	Optimal bool `json:"optimal"`
+}
+
+// ErrNoPlan is returned when no servings of the products meet the targets and limits
+var ErrNoPlan = errors.New("no plan meets the targets within the limits, try loosening them or adding products")
+
+// candidate is a product that can be planned, with its per-serving macros and cost
+type candidate struct {
+	p       *product.Product
+	serving product.Macros
+	cost    float64
+}
+
+func newCandidate(p *product.Product) (candidate, bool) {
+	serving, ok := p.Serving()
+	if !ok || serving.Kcal() <= 0 {
+		return candidate{}, false
+	}
+	cost, ok := p.CostPerServing()
+	if !ok {
+		return candidate{}, false
+	}
+	return candidate{p: p, serving: serving, cost: cost}, true
+}
+
+// Make returns the cheapest whole daily servings of products meeting opts. Products
+// without macros or a cost per serving are ignored.
+func Make(products []*product.Product, opts Options) (Plan, error) {
+	if opts.Days <= 0 {
+		opts.Days = 7
+	}
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = 5000
+	}
+	candidates := []candidate{}
+	for _, p := range products {
+		if c, ok := newCandidate(p); ok {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return Plan{}, errors.New("no products have macros and a price per serving")
+	}
+
+	n := len(candidates)
+	problem := lp.Problem{Objective: make([]float64, n), Integer: make([]bool, n)}
+	kcal, protein, carbs, fat := make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+	for i, c := range candidates {
+		problem.Objective[i] = c.cost
+		problem.Integer[i] = true
+		kcal[i], protein[i], carbs[i], fat[i] = c.serving.Kcal(), c.serving.Protein(), c.serving.Carbs(), c.serving.Fat()
+	}
+	within := func(coeffs []float64, r Range) {
+		if r.Min > 0 {
+			problem.Constraints = append(problem.Constraints, lp.Constraint{Coeffs: coeffs, Op: lp.GE, RHS: r.Min})
+		}
+		if r.Max != nil {
+			problem.Constraints = append(problem.Constraints, lp.Constraint{Coeffs: coeffs, Op: lp.LE, RHS: *r.Max})
+		}
+	}
+	within(kcal, opts.Kcal)
+	within(protein, opts.Protein)
+	within(carbs, opts.Carbs)
+	within(fat, opts.Fat)
+	if opts.Budget > 0 {
+		daily := opts.Budget / float64(opts.Days)
+		within(problem.Objective, Range{Max: &daily})
+	}
+	if opts.MaxServings > 0 {
+		problem.Upper = make([]float64, n)
+		for i := range problem.Upper {
+			problem.Upper[i] = float64(opts.MaxServings)
+		}
+	}
+	if len(problem.Constraints) == 0 {
+		return Plan{}, errors.New("no targets given")
+	}
+
+	solution, err := problem.SolveInteger(opts.MaxNodes)
+	if err == lp.ErrInfeasible {
+		return Plan{}, ErrNoPlan
+	}
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{Days: opts.Days, Lines: []Line{}, Optimal: solution.Optimal}
+	for i, c := range candidates {
+		perDay := int(math.Round(solution.X[i]))
+		if perDay <= 0 {
+			continue
+		}
+		line := Line{
+			ID:             c.p.ID(),
+			Name:           c.p.Name(),
+			Serving:        c.serving.Per(),
+			ServingsPerDay: perDay,
+			Servings:       perDay * opts.Days,
+			CostPerServing: c.cost,
+			PackPrice:      c.p.Price(),
+		}
+		line.Packs = int(math.Ceil(float64(line.Servings)/c.p.Servings() - 1e-9))
+		line.Cost = float64(line.Packs) * line.PackPrice
+		plan.Lines = append(plan.Lines, line)
+
+		servings := float64(perDay)
+		plan.Daily.Kcal += c.serving.Kcal() * servings
+		plan.Daily.Protein += c.serving.Protein() * servings
+		plan.Daily.Carbs += c.serving.Carbs() * servings
+		plan.Daily.Fat += c.serving.Fat() * servings
+		plan.ServingCost += c.cost * float64(line.Servings)
+		plan.ShoppingCost += line.Cost
+	}
+	sort.SliceStable(plan.Lines, func(i, j int) bool { return plan.Lines[i].Cost > plan.Lines[j].Cost })
+	return plan, nil
+}
@@ -0,0 +1,109 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestMake(t *testing.T) {
+	raws := map[string]string{
+		"100000001": `{"pageTitle":"Tesco Chicken Breast 650G","product":{"id":"100000001","price":3.9,"details":{` +
+			`"packSize":[{"value":"650","units":"g"}],"numberOfUses":"Typically 4 servings","nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},` +
+			`{"name":"Energy","perComp":"440kJ / 104kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"0g","perServing":"-"},` +
+			`{"name":"Protein","perComp":"24.0g","perServing":"-"}]}}}`,
+		"100000002": `{"pageTitle":"Tesco Long Grain Rice 1Kg","product":{"id":"100000002","price":1,"details":{` +
+			`"packSize":[{"value":"1","units":"kg"}],"numberOfUses":"10 servings","nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},` +
+			`{"name":"Energy","perComp":"1480kJ / 350kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"1.0g","perServing":"-"},` +
+			`{"name":"Protein","perComp":"7.0g","perServing":"-"}]}}}`,
+		"100000003": `{"pageTitle":"Water","product":{"id":"100000003"}}`,
+	}
+	products := []*product.Product{}
+	for _, id := range []string{"100000001", "100000002", "100000003"} {
+		p, err := product.NewProduct(raws[id], product.IDToURL(id))
+		if err != nil {
+			t.Fatalf("NewProduct() error = %v", err)
+		}
+		products = append(products, p)
+	}
+	kcal, protein := Range{Min: 1500}, Range{Min: 100}
+	kcalMax, noFat := 2500.0, 0.0
+	kcal.Max = &kcalMax
+
+	tests := []struct {
+		name        string
+		fat         Range
+		maxServings int
+		budget      float64
+		wantErr     error
+		want        string
+	}{
+		// two chicken servings and four of rice is the cheapest way to 100g protein and 1500kcal
+		{"cheapest", Range{}, 6, 0, nil,
+			"100000001 2/day 14 servings 4 packs £15.60, 100000002 4/day 28 servings 3 packs £3.00; 1738kcal 106g protein; £16.45 eaten £18.60 bought"},
+		{"too few servings", Range{}, 1, 0, ErrNoPlan, ""},
+		{"over budget", Range{}, 6, 14, ErrNoPlan, ""},
+		// a maximum of 0 leaves out the rice, and chicken alone needs more than 6 servings
+		{"no fat", Range{Max: &noFat}, 6, 0, ErrNoPlan, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{
+				Targets:     Targets{Kcal: kcal, Protein: protein, Fat: tt.fat},
+				Days:        7,
+				MaxServings: tt.maxServings,
+				Budget:      tt.budget,
+			}
+			got, err := Make(products, opts)
+			if err != tt.wantErr {
+				t.Fatalf("Make() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !got.Optimal {
+				t.Errorf("Make() is not optimal")
+			}
+			lines := []string{}
+			for _, l := range got.Lines {
+				lines = append(lines, fmt.Sprintf("%v %v/day %v servings %v packs £%.2f", l.ID, l.ServingsPerDay, l.Servings, l.Packs, l.Cost))
+			}
+			summary := fmt.Sprintf("%v; %.0fkcal %.0fg protein; £%.2f eaten £%.2f bought",
+				strings.Join(lines, ", "), got.Daily.Kcal, got.Daily.Protein, got.ServingCost, got.ShoppingCost)
+			if summary != tt.want {
+				t.Errorf("Make() = %v, want %v", summary, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{"1800:2200", "1800:2200", false},
+		{"150", "150:", false},
+		{"150:", "150:", false},
+		{":70", "0:70", false},
+		{":0", "0:0", false},
+		{"", "0:", false},
+		{"200:100", "0:", true},
+		{"lots", "0:", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseRange(tt.s)
+			if (err != nil) != tt.wantErr || got.String() != tt.want {
+				t.Errorf("ParseRange() = %v, %v, want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package product
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	netAmount *regexp.Regexp = regexp.MustCompile(`(?i)(?:(\d+)\s*x\s*)?(\d+(?:\.\d+)?)\s*(kg|g|ml|cl|l|litres?)\b`)
+	usesCount *regexp.Regexp = regexp.MustCompile(`(\d+(?:\.\d+)?)`)
+)
+
+// Pack is how much a product's pack contains
+type Pack struct {
+	size          float64
+	unit          string
+	netContents   string
+	drainedWeight float64
+	uses          string
+	servings      float64
+	items         int
+}
+
+// Size returns the total contents of the pack in Unit, or 0 if unknown
+func (p Pack) Size() float64 { return p.size }
+
+// Unit returns the unit of Size, "g" or "ml", or empty if unknown
+func (p Pack) Unit() string { return p.unit }
+
+// NetContents returns the net contents as labelled, e.g. "4 x 330ml ℮"
+func (p Pack) NetContents() string { return p.netContents }
+
+// DrainedWeight returns grams of drained contents of products packed in liquid, or 0
+func (p Pack) DrainedWeight() float64 { return p.drainedWeight }
+
+// Uses returns the number of uses as labelled, e.g. "This pack contains 4 servings"
+func (p Pack) Uses() string { return p.uses }
+
+// Servings returns the number of servings labelled on the pack, or 0 if not given
+func (p Pack) Servings() float64 { return p.servings }
+
+// Items returns the number of items in a multipack, or 0 if it is not one
+func (p Pack) Items() int { return p.items }
+
+// newPack parses the packSize, netContents, drainedWeight, numberOfUses and
+// multiPackDetails of a product. The size is taken from packSize, falling back to
+// netContents, with kilograms converted to grams and litres to millilitres. The items in
+// a multipack are counted from netContents, such as "4 x 330ml", falling back to the
+// number of multiPackDetails.
+func newPack(packSizes []gjson.Result, netContents, drainedWeight, uses string, multiPack gjson.Result) Pack {
+	pack := Pack{
+		netContents: strings.TrimSpace(netContents),
+		uses:        strings.TrimSpace(uses),
+	}
+	pack.servings, _ = parseFloat(usesCount, uses)
+	for _, size := range packSizes {
+		amount, unit := toBaseUnit(size.Get("value").Float(), size.Get("units").String())
+		if amount > 0 && (pack.unit == "" || pack.unit == unit) {
+			pack.size += amount
+			pack.unit = unit
+		}
+	}
+	netSize, netUnit, netItems := parseNetAmount(netContents)
+	if pack.size == 0 {
+		pack.size, pack.unit = netSize, netUnit
+	}
+	if weight, unit, _ := parseNetAmount(drainedWeight); unit == "g" {
+		pack.drainedWeight = weight
+	}
+	// multiPackDetails lists the kinds of item in a multipack rather than each item, so the
+	// count in the net contents is used when the label gives one
+	if netItems > 1 {
+		pack.items = netItems
+	} else if kinds := len(multiPack.Array()); kinds > 1 {
+		pack.items = kinds
+	}
+	return pack
+}
+
+// parseNetAmount returns the total amount, unit and number of items of a label such as
+// "255g" or "4 x 330ml", with the amount in grams or millilitres
+func parseNetAmount(s string) (float64, string, int) {
+	match := netAmount.FindStringSubmatch(s)
+	if match == nil {
+		return 0, "", 0
+	}
+	amount, _ := strconv.ParseFloat(match[2], 64)
+	items, _ := strconv.Atoi(match[1])
+	if items > 1 {
+		amount *= float64(items)
+	}
+	size, unit := toBaseUnit(amount, match[3])
+	return size, unit, items
+}
+
+// toBaseUnit converts an amount to grams or millilitres, returning 0 and an empty unit
+// for units that are not a weight or volume, such as "SNGL"
+func toBaseUnit(amount float64, unit string) (float64, string) {
+	if amount <= 0 {
+		return 0, ""
+	}
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "g", "gram", "grams":
+		return amount, "g"
+	case "kg":
+		return amount * 1000, "g"
+	case "ml":
+		return amount, "ml"
+	case "cl":
+		return amount * 10, "ml"
+	case "l", "lt", "ltr", "litre", "litres":
+		return amount * 1000, "ml"
+	}
+	return 0, ""
+}
+
+// Scale returns the values of m for amount of its Unit, or false if m has no Size
+func (m Macros) Scale(amount float64) (Macros, bool) {
+	if m.size <= 0 || amount <= 0 {
+		return Macros{}, false
+	}
+	factor := amount / m.size
+	return Macros{
+		per:       "Per " + strconv.FormatFloat(amount, 'f', -1, 64) + m.unit,
+		size:      amount,
+		unit:      m.unit,
+		carbs:     m.carbs * factor,
+		protein:   m.protein * factor,
+		fat:       m.fat * factor,
+		kcal:      m.kcal * factor,
+		kj:        m.kj * factor,
+		saturates: m.saturates * factor,
+		sugars:    m.sugars * factor,
+		fibre:     m.fibre * factor,
+		salt:      m.salt * factor,
+		known:     m.known,
+	}, true
+}
+
+// PerPack returns the values for the whole pack, scaled from PerComp by the pack size,
+// or false if the pack size is unknown or in a different unit to PerComp
+func (p *Product) PerPack() (Macros, bool) {
+	if p.pack.size <= 0 || p.pack.unit != p.perComp.unit || !p.perComp.HasAny() {
+		return Macros{}, false
+	}
+	return p.perComp.Scale(p.pack.size)
+}
+
+// Servings returns the number of servings in the pack as labelled, or else estimated from
+// the pack and serving sizes, or 0 if unknown
+func (p *Product) Servings() float64 {
+	if p.pack.servings > 0 {
+		return p.pack.servings
+	}
+	if p.pack.size > 0 && p.perServing.size > 0 && p.pack.unit == p.perServing.unit {
+		return p.pack.size / p.perServing.size
+	}
+	return 0
+}
+
+// Serving returns the values for a serving, as labelled or else derived by dividing the
+// whole pack by the number of Servings, or false if neither is known
+func (p *Product) Serving() (Macros, bool) {
+	if p.perServing.size > 0 && p.perServing.HasAny() {
+		return p.perServing, true
+	}
+	servings := p.Servings()
+	if servings <= 0 {
+		return Macros{}, false
+	}
+	pack, ok := p.PerPack()
+	if !ok {
+		return Macros{}, false
+	}
+	return pack.Scale(pack.size / servings)
+}
+
+// CostPerServing returns the shelf price divided by the number of Servings, or false if
+// either is unknown
+func (p *Product) CostPerServing() (float64, bool) {
+	servings := p.Servings()
+	if servings <= 0 || p.price <= 0 {
+		return 0, false
+	}
+	return p.price / servings, true
+}
@@ -14,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/mattburman/tesco/pkg/ingredients"
 	"github.com/tidwall/gjson"
 )
 
@@ -21,21 +22,88 @@ var (
 	productf          string         = "https://www.tesco.com/groceries/en-GB/products/%v"
 	dataRegexp        *regexp.Regexp = regexp.MustCompile(`data-props="({.*})"`)
 	invalidProductIDf string         = "%v is an invalid productID"
-	perGrams          *regexp.Regexp = regexp.MustCompile(`^Per (?P<grams>\d+)g$`)
-	perServingGrams   *regexp.Regexp = regexp.MustCompile(`.*\((?P<grams>\d+)g\).*`)
+	perAmount         *regexp.Regexp = regexp.MustCompile(`(?i)^Per (?P<amount>\d+(?:\.\d+)?)\s*(?P<unit>g|ml|l)$`)
+	servingAmount     *regexp.Regexp = regexp.MustCompile(`(?i)(?:^|[\s(])(?P<amount>\d+(?:\.\d+)?)\s*(?P<unit>g|ml|l)\b`)
 	floatGrams        *regexp.Regexp = regexp.MustCompile(`(?P<grams>\d+(\.\d+)?)g`)
 	floatKcal         *regexp.Regexp = regexp.MustCompile(`\d+kJ / (?P<kcal>\d+)kcal`)
+	floatKJ           *regexp.Regexp = regexp.MustCompile(`(?P<kj>\d+(\.\d+)?)\s*kJ`)
 )
 
 type Macros struct {
-	per     string
-	size    float64
-	carbs   float64
-	protein float64
-	fat     float64
-	kcal    float64
+	per       string
+	size      float64
+	unit      string
+	carbs     float64
+	protein   float64
+	fat       float64
+	kcal      float64
+	kj        float64
+	saturates float64
+	sugars    float64
+	fibre     float64
+	salt      float64
+	known     Nutrient
 }
 
+// Nutrient is a set of the nutrients Macros may give
+type Nutrient uint
+
+const (
+	Energy Nutrient = 1 << iota
+	Fat
+	Saturates
+	Carbs
+	Sugars
+	Fibre
+	Protein
+	Salt
+)
+
+// Per returns the label the values are given for, e.g. "Per 100g"
+func (m Macros) Per() string { return m.per }
+
+// Size returns the amount the values are given for in Unit, or 0 if unknown
+func (m Macros) Size() float64 { return m.size }
+
+// Unit returns the unit of Size, "g" or "ml", or empty if unknown
+func (m Macros) Unit() string { return m.unit }
+
+// IsLiquid reports whether the values are given for a volume rather than a weight
+func (m Macros) IsLiquid() bool { return m.unit == "ml" }
+
+// Has reports whether all of n are given, telling a nutrient that is absent from one that is 0
+func (m Macros) Has(n Nutrient) bool { return n != 0 && m.known&n == n }
+
+// HasAny reports whether any nutrient is given
+func (m Macros) HasAny() bool { return m.known != 0 }
+
+// Carbs returns grams of carbohydrate
+func (m Macros) Carbs() float64 { return m.carbs }
+
+// Protein returns grams of protein
+func (m Macros) Protein() float64 { return m.protein }
+
+// Fat returns grams of fat
+func (m Macros) Fat() float64 { return m.fat }
+
+// Kcal returns the energy in kcal
+func (m Macros) Kcal() float64 { return m.kcal }
+
+// KJ returns the energy in kJ
+func (m Macros) KJ() float64 { return m.kj }
+
+// Saturates returns grams of saturated fat
+func (m Macros) Saturates() float64 { return m.saturates }
+
+// Sugars returns grams of sugars
+func (m Macros) Sugars() float64 { return m.sugars }
+
+// Fibre returns grams of fibre
+func (m Macros) Fibre() float64 { return m.fibre }
+
+// Salt returns grams of salt
+func (m Macros) Salt() float64 { return m.salt }
+
 type Source struct {
 	url  string
 	id   string
@@ -44,14 +112,84 @@ type Source struct {
 
 type Product struct {
 	name                            string
+	gtin                            string
 	source                          Source
 	description                     []string
+	ingredients                     []ingredients.Ingredient
+	brand                           string
+	superDepartment                 string
+	department                      string
+	aisle                           string
+	shelf                           string
+	price                           float64
+	unitPrice                       float64
+	unitOfMeasure                   string
 	raw                             string
 	hashOfRawValueLastUsedToCompute string
 	perComp                         Macros
 	perServing                      Macros
+	pack                            Pack
 }
 
+// Name returns the product title
+func (p *Product) Name() string { return p.name }
+
+// ID returns the Tesco product ID
+func (p *Product) ID() string { return p.source.id }
+
+// URL returns the product page the product was sourced from
+func (p *Product) URL() string { return p.source.url }
+
+// GTIN returns the barcode number as given by Tesco, usually 14 digits with leading zeros
+func (p *Product) GTIN() string { return p.gtin }
+
+// Description returns the lines of the product description
+func (p *Product) Description() []string { return p.description }
+
+// Ingredients returns the parsed ingredients list, empty for products without one
+func (p *Product) Ingredients() []ingredients.Ingredient { return p.ingredients }
+
+// Brand returns the brand name, e.g. "TESCO"
+func (p *Product) Brand() string { return p.brand }
+
+// Categories returns the super department, department, aisle and shelf the product is listed under
+func (p *Product) Categories() []string {
+	return []string{p.superDepartment, p.department, p.aisle, p.shelf}
+}
+
+// Department returns the department name, e.g. "Fresh Meat & Poultry"
+func (p *Product) Department() string { return p.department }
+
+// Aisle returns the aisle name, e.g. "Fresh Beef"
+func (p *Product) Aisle() string { return p.aisle }
+
+// Shelf returns the shelf name, e.g. "Beef Steaks"
+func (p *Product) Shelf() string { return p.shelf }
+
+// Price returns the shelf price in pounds
+func (p *Product) Price() float64 { return p.price }
+
+// UnitPrice returns the price in pounds per UnitOfMeasure
+func (p *Product) UnitPrice() float64 { return p.unitPrice }
+
+// UnitOfMeasure returns the unit UnitPrice is given for, e.g. "kg"
+func (p *Product) UnitOfMeasure() string { return p.unitOfMeasure }
+
+// Raw returns the raw json the product was constructed from
+func (p *Product) Raw() string { return p.raw }
+
+// Hash returns the sha1 of the raw json the product was constructed from
+func (p *Product) Hash() string { return p.hashOfRawValueLastUsedToCompute }
+
+// PerComp returns the macros for the comparison size, usually 100g
+func (p *Product) PerComp() Macros { return p.perComp }
+
+// PerServing returns the macros for a single serving
+func (p *Product) PerServing() Macros { return p.perServing }
+
+// Pack returns the pack size, net contents and number of servings
+func (p *Product) Pack() Pack { return p.pack }
+
 // NewProduct constructs a Product from a raw tesco json response string
 func NewProduct(raw string, url string) (*Product, error) {
 	results := gjson.GetMany(
@@ -61,6 +199,21 @@ func NewProduct(raw string, url string) (*Product, error) {
 		"product.details.nutritionInfo.#.name",
 		"product.details.nutritionInfo.#.perComp",
 		"product.details.nutritionInfo.#.perServing",
+		"product.price",
+		"product.unitPrice",
+		"product.unitOfMeasure",
+		"product.brandName",
+		"superDepartmentName",
+		"departmentName",
+		"aisleName",
+		"shelfName",
+		"product.gtin",
+		"product.details.ingredients",
+		"product.details.packSize",
+		"product.details.netContents",
+		"product.details.drainedWeight",
+		"product.details.numberOfUses",
+		"product.multiPackDetails",
 	)
 	name := results[0].String()
 
@@ -77,6 +230,11 @@ func NewProduct(raw string, url string) (*Product, error) {
 		description[i] = result.String()
 	}
 
+	ingredientLines := []string{}
+	for _, line := range results[14].Array() {
+		ingredientLines = append(ingredientLines, line.String())
+	}
+
 	h := sha1.New()
 	h.Write([]byte(raw))
 	hashOfRawValueLastUsedToCompute := fmt.Sprintf("%x", h.Sum(nil))
@@ -90,75 +248,120 @@ func NewProduct(raw string, url string) (*Product, error) {
 		nutrientIndices[nutrient.String()] = i
 	}
 
+	// nutrient returns the perComp and perServing values for name, or empty strings if absent
+	nutrient := func(name string) (string, string) {
+		i, ok := nutrientIndices[name]
+		if !ok || i >= len(nutrientPerComps) || i >= len(nutrientPerServings) {
+			return "", ""
+		}
+		return nutrientPerComps[i].String(), nutrientPerServings[i].String()
+	}
+
 	perComp := Macros{}
 	perServing := Macros{}
 
-	i := nutrientIndices["Typical Values"]
-	perComp.per = nutrientPerComps[i].String()
-	perServing.per = nutrientPerServings[i].String()
-	match := perGrams.FindSubmatch([]byte(perComp.per))
-	if len(match) == 2 {
-		perComp.size, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
-	match = perServingGrams.FindSubmatch([]byte(perServing.per))
-	if len(match) == 2 {
-		perServing.size, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
+	perComp.per, perServing.per = nutrient("Typical Values")
+	perComp.size, perComp.unit = parseAmount(perAmount, perComp.per)
+	perServing.size, perServing.unit = parseAmount(servingAmount, perServing.per)
 
-	i = nutrientIndices["Fat"]
-	match = floatGrams.FindSubmatch([]byte(nutrientPerComps[i].String()))
-	if len(match) > 1 {
-		perComp.fat, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
-	match = floatGrams.FindSubmatch([]byte(nutrientPerServings[i].String()))
-	if len(match) > 1 {
-		perServing.fat, _ = strconv.ParseFloat(string(match[1]), 64)
+	// parse sets a nutrient of both from their values, marking it known where it matched
+	parse := func(n Nutrient, re *regexp.Regexp, comp, serving string, compValue, servingValue *float64) {
+		var ok bool
+		if *compValue, ok = parseFloat(re, comp); ok {
+			perComp.known |= n
+		}
+		if *servingValue, ok = parseFloat(re, serving); ok {
+			perServing.known |= n
+		}
 	}
 
-	i = nutrientIndices["Protein"]
-	match = floatGrams.FindSubmatch([]byte(nutrientPerComps[i].String()))
-	if len(match) > 1 {
-		perComp.protein, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
-	match = floatGrams.FindSubmatch([]byte(nutrientPerServings[i].String()))
-	if len(match) > 1 {
-		perServing.protein, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
+	comp, serving := nutrient("Fat")
+	parse(Fat, floatGrams, comp, serving, &perComp.fat, &perServing.fat)
 
-	i = nutrientIndices["Carbohydrate"]
-	match = floatGrams.FindSubmatch([]byte(nutrientPerComps[i].String()))
-	if len(match) > 1 {
-		perComp.carbs, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
-	match = floatGrams.FindSubmatch([]byte(nutrientPerServings[i].String()))
-	if len(match) > 1 {
-		perServing.carbs, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
+	comp, serving = nutrient("Protein")
+	parse(Protein, floatGrams, comp, serving, &perComp.protein, &perServing.protein)
 
-	i = nutrientIndices["Energy"]
-	match = floatKcal.FindSubmatch([]byte(nutrientPerComps[i].String()))
-	fmt.Println(string(match[1]))
-	if len(match) > 1 {
-		perComp.kcal, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
-	match = floatKcal.FindSubmatch([]byte(nutrientPerServings[i].String()))
-	if len(match) > 1 {
-		perServing.kcal, _ = strconv.ParseFloat(string(match[1]), 64)
-	}
+	comp, serving = nutrient("Carbohydrate")
+	parse(Carbs, floatGrams, comp, serving, &perComp.carbs, &perServing.carbs)
+
+	comp, serving = nutrient("Energy")
+	parse(Energy, floatKcal, comp, serving, &perComp.kcal, &perServing.kcal)
+	parse(Energy, floatKJ, comp, serving, &perComp.kj, &perServing.kj)
+
+	comp, serving = nutrient("Saturates")
+	parse(Saturates, floatGrams, comp, serving, &perComp.saturates, &perServing.saturates)
+
+	comp, serving = nutrient("Sugars")
+	parse(Sugars, floatGrams, comp, serving, &perComp.sugars, &perServing.sugars)
+
+	comp, serving = nutrient("Fibre")
+	parse(Fibre, floatGrams, comp, serving, &perComp.fibre, &perServing.fibre)
+
+	comp, serving = nutrient("Salt")
+	parse(Salt, floatGrams, comp, serving, &perComp.salt, &perServing.salt)
 
 	product := Product{
 		name:                            name,
+		gtin:                            results[13].String(),
 		source:                          source,
 		description:                     description,
+		ingredients:                     ingredients.Parse(ingredientLines),
+		brand:                           results[8].String(),
+		superDepartment:                 results[9].String(),
+		department:                      results[10].String(),
+		aisle:                           results[11].String(),
+		shelf:                           results[12].String(),
+		price:                           results[5].Float(),
+		unitPrice:                       results[6].Float(),
+		unitOfMeasure:                   results[7].String(),
 		raw:                             raw,
 		hashOfRawValueLastUsedToCompute: hashOfRawValueLastUsedToCompute,
 		perComp:                         perComp,
 		perServing:                      perServing,
+		pack:                            newPack(results[15].Array(), results[16].String(), results[17].String(), results[18].String(), results[19]),
 	}
 
 	return &product, nil
 }
 
+// parseFloat returns the first submatch of re in s as a float, or false if there is no match
+func parseFloat(re *regexp.Regexp, s string) (float64, bool) {
+	match := re.FindStringSubmatch(s)
+	if len(match) < 2 {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(match[1], 64)
+	return f, err == nil
+}
+
+// parseAmount returns the amount and unit matched by re in s, such as 100g or 250ml, with
+// litres converted to ml, or 0 and an empty unit if there is no match
+func parseAmount(re *regexp.Regexp, s string) (float64, string) {
+	match := re.FindStringSubmatch(s)
+	if len(match) < 3 {
+		return 0, ""
+	}
+	amount, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || amount <= 0 {
+		return 0, ""
+	}
+	switch unit := strings.ToLower(match[2]); unit {
+	case "l":
+		return amount * 1000, "ml"
+	default:
+		return amount, unit
+	}
+}
+
+// FromResources constructs a Product from the resources json of a product page, as stored by a scrape
+func FromResources(resources string, url string) (*Product, error) {
+	data := gjson.Get(resources, "productDetails.data")
+	if !data.Exists() {
+		return nil, errors.New("unable to extract productDetails.data")
+	}
+	return NewProduct(data.Raw, url)
+}
+
 // GetProduct returns the product data
 // or an error for parameter, network or request failures
 func GetProduct(id string) (*string, error) {
@@ -170,7 +373,7 @@ func GetProduct(id string) (*string, error) {
 		return nil, fmt.Errorf(invalidProductIDf, id)
 	}
 
-	productURL := fmt.Sprintf(productf, id)
+	productURL := IDToURL(id)
 
 	resp, err := http.Get(productURL)
 	if err != nil {
@@ -258,6 +461,11 @@ func GetUnfetchedProductIDs(db *sql.DB, toFetchProductIDs *[]string) (*[]string,
 
 var urlRegex *regexp.Regexp = regexp.MustCompile(`https://www.tesco.com/groceries/en-GB/products/(?P<ID>\d+)`)
 
+// IDToURL returns the product page URL for a product ID
+func IDToURL(id string) string {
+	return fmt.Sprintf(productf, id)
+}
+
 // URLToID extracts the ID from a product URL
 func URLToID(url string) (string, error) {
 	match := urlRegex.FindSubmatch([]byte(url))
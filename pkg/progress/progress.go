@@ -0,0 +1,227 @@
+// Package progress tracks the progress of a scrape and reports it to a terminal or log
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts the work done by a scrape. All methods are safe for concurrent use
+// and a nil *Stats ignores updates, so callers can pass nil to disable tracking.
+type Stats struct {
+	categories int64
+	pages      int64
+	queued     int64
+	fetched    int64
+	skipped    int64
+	failed     int64
+	inserted   int64
+	start      time.Time
+}
+
+// Snapshot is a point in time copy of Stats
+type Snapshot struct {
+	Categories int64
+	Pages      int64
+	Queued     int64
+	Fetched    int64
+	Skipped    int64
+	Failed     int64
+	Inserted   int64
+	Elapsed    time.Duration
+}
+
+// New returns Stats with the clock started
+func New() *Stats {
+	return &Stats{start: time.Now()}
+}
+
+func (s *Stats) add(counter *int64, n int) {
+	atomic.AddInt64(counter, int64(n))
+}
+
+// AddCategory records a category being started
+func (s *Stats) AddCategory() {
+	if s != nil {
+		s.add(&s.categories, 1)
+	}
+}
+
+// AddPage records a listing page being visited
+func (s *Stats) AddPage() {
+	if s != nil {
+		s.add(&s.pages, 1)
+	}
+}
+
+// AddQueued records n product pages queued for fetching
+func (s *Stats) AddQueued(n int) {
+	if s != nil {
+		s.add(&s.queued, n)
+	}
+}
+
+// AddFetched records a product page being fetched
+func (s *Stats) AddFetched() {
+	if s != nil {
+		s.add(&s.fetched, 1)
+	}
+}
+
+// AddSkipped records n products skipped because they are already stored
+func (s *Stats) AddSkipped(n int) {
+	if s != nil {
+		s.add(&s.skipped, n)
+	}
+}
+
+// AddFailed records a failed request or extraction
+func (s *Stats) AddFailed() {
+	if s != nil {
+		s.add(&s.failed, 1)
+	}
+}
+
+// AddInserted records a product being persisted
+func (s *Stats) AddInserted() {
+	if s != nil {
+		s.add(&s.inserted, 1)
+	}
+}
+
+// Snapshot returns the current counts
+func (s *Stats) Snapshot() Snapshot {
+	if s == nil {
+		return Snapshot{}
+	}
+	return Snapshot{
+		Categories: atomic.LoadInt64(&s.categories),
+		Pages:      atomic.LoadInt64(&s.pages),
+		Queued:     atomic.LoadInt64(&s.queued),
+		Fetched:    atomic.LoadInt64(&s.fetched),
+		Skipped:    atomic.LoadInt64(&s.skipped),
+		Failed:     atomic.LoadInt64(&s.failed),
+		Inserted:   atomic.LoadInt64(&s.inserted),
+		Elapsed:    time.Since(s.start),
+	}
+}
+
+// Rate returns the number of requests made per second
+func (s Snapshot) Rate() float64 {
+	secs := s.Elapsed.Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(s.Pages+s.Fetched+s.Failed) / secs
+}
+
+// ETA estimates the time left to fetch the queued products, or 0 if unknown
+func (s Snapshot) ETA() time.Duration {
+	remaining := s.Queued - s.Fetched - s.Failed
+	done := s.Fetched + s.Failed
+	if remaining <= 0 || done == 0 {
+		return 0
+	}
+	perProduct := s.Elapsed / time.Duration(done)
+	return perProduct * time.Duration(remaining)
+}
+
+// String formats the snapshot as a single line
+func (s Snapshot) String() string {
+	eta := "-"
+	if d := s.ETA(); d > 0 {
+		eta = d.Round(time.Second).String()
+	}
+	return fmt.Sprintf(
+		"categories %d | pages %d | queued %d | fetched %d | skipped %d | failed %d | inserted %d | %.1f req/s | eta %v",
+		s.Categories, s.Pages, s.Queued, s.Fetched, s.Skipped, s.Failed, s.Inserted, s.Rate(), eta,
+	)
+}
+
+// IsTerminal reports whether f is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Reporter periodically writes Stats to w until stopped. On a terminal the line is
+// redrawn in place, otherwise a summary line is written every interval.
+type Reporter struct {
+	stats    *Stats
+	w        io.Writer
+	tty      bool
+	interval time.Duration
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewReporter returns a Reporter for stats writing to w
+func NewReporter(stats *Stats, w io.Writer, tty bool) *Reporter {
+	interval := 10 * time.Second
+	if tty {
+		interval = 250 * time.Millisecond
+	}
+	return &Reporter{
+		stats:    stats,
+		w:        w,
+		tty:      tty,
+		interval: interval,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start begins reporting in the background
+func (r *Reporter) Start() {
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		width := 0
+		for {
+			select {
+			case <-ticker.C:
+				width = r.write(width)
+			case <-r.done:
+				line := r.stats.Snapshot().String()
+				if r.tty {
+					fmt.Fprintf(r.w, "\r%v%v\n", line, padding(width, len(line)))
+				} else {
+					fmt.Fprintln(r.w, line)
+				}
+				return
+			}
+		}
+	}()
+}
+
+func (r *Reporter) write(width int) int {
+	line := r.stats.Snapshot().String()
+	if !r.tty {
+		fmt.Fprintln(r.w, line)
+		return 0
+	}
+	fmt.Fprintf(r.w, "\r%v%v", line, padding(width, len(line)))
+	return len(line)
+}
+
+// Stop writes a final summary and stops reporting
+func (r *Reporter) Stop() {
+	close(r.done)
+	<-r.stopped
+}
+
+// padding returns the spaces needed to blank out the rest of a previously wider line
+func padding(previous, current int) string {
+	if previous <= current {
+		return ""
+	}
+	return strings.Repeat(" ", previous-current)
+}
@@ -0,0 +1,53 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotRateAndETA(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot Snapshot
+		wantRate float64
+		wantETA  time.Duration
+	}{
+		{
+			"nothing done",
+			Snapshot{Queued: 10, Elapsed: time.Second},
+			0,
+			0,
+		},
+		{
+			"half done",
+			Snapshot{Pages: 1, Queued: 10, Fetched: 4, Failed: 1, Elapsed: 10 * time.Second},
+			0.6,
+			10 * time.Second,
+		},
+		{
+			"all done",
+			Snapshot{Pages: 2, Queued: 3, Fetched: 3, Elapsed: 5 * time.Second},
+			1,
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.snapshot.Rate(); got != tt.wantRate {
+				t.Errorf("Rate() = %v, want %v", got, tt.wantRate)
+			}
+			if got := tt.snapshot.ETA(); got != tt.wantETA {
+				t.Errorf("ETA() = %v, want %v", got, tt.wantETA)
+			}
+		})
+	}
+}
+
+func TestNilStats(t *testing.T) {
+	var s *Stats
+	s.AddFetched()
+	s.AddQueued(3)
+	if got := s.Snapshot(); got != (Snapshot{}) {
+		t.Errorf("Snapshot() of nil Stats = %+v, want zero value", got)
+	}
+}
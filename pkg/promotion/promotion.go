@@ -0,0 +1,192 @@
+// Package promotion parses the offers on a product, such as multibuys and Clubcard prices,
+// into structured offers with the effective price they give
+package promotion
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/tidwall/gjson"
+)
+
+// Kind is the structure of an offer
+type Kind string
+
+const (
+	// FixedPrice is a reduced price for one item, e.g. "Now £2.00"
+	FixedPrice Kind = "fixed-price"
+	// MultiBuy is a price for several items, e.g. "Any 2 for £5.00" or "3 for 2"
+	MultiBuy Kind = "multibuy"
+	// PercentOff is a discount, e.g. "Save 25%" or "Half Price"
+	PercentOff Kind = "percent-off"
+	// Clubcard is a reduced price for one item with a Clubcard, e.g. "£1.50 Clubcard Price"
+	Clubcard Kind = "clubcard"
+	// Other is an offer whose text is not understood
+	Other Kind = "other"
+)
+
+// Kinds lists the kinds of offer
+var Kinds = []Kind{FixedPrice, MultiBuy, PercentOff, Clubcard, Other}
+
+// Offer is a promotion on a product
+type Offer struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Text string `json:"text"`
+	Kind Kind   `json:"kind"`
+	// Clubcard is set when the offer needs a Clubcard, including Clubcard multibuys
+	Clubcard bool      `json:"clubcard"`
+	Start    time.Time `json:"start,omitempty"`
+	End      time.Time `json:"end,omitempty"`
+	// Quantity is the number of items the offer is for, 1 unless a multibuy
+	Quantity int `json:"quantity"`
+	// Price is the price of Quantity items, or 0 for percentage and "3 for 2" offers
+	Price float64 `json:"price,omitempty"`
+	// Pay is the number of items paid for in a "3 for 2" multibuy
+	Pay     int     `json:"pay,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+}
+
+var (
+	clubcardText = regexp.MustCompile(`(?i)\s*clubcard\s+price\s*`)
+	multiPrice   = regexp.MustCompile(`(?i)(\d+)\s+for\s+(£\d+(?:\.\d{1,2})?|\d+p)\b`)
+	multiCount   = regexp.MustCompile(`(?i)(\d+)\s+for\s+(\d+)\b`)
+	buyGetFree   = regexp.MustCompile(`(?i)buy\s+(\d+)\s+get\s+(\d+)\s+free`)
+	percentOff   = regexp.MustCompile(`(?i)(?:save\s+)?(\d+(?:\.\d+)?)%(?:\s+off)?`)
+	halfPrice    = regexp.MustCompile(`(?i)\bhalf\s+price\b`)
+	nowPrice     = regexp.MustCompile(`(?i)(?:^|\bnow\s+|\bonly\s+)(£\d+(?:\.\d{1,2})?|\d+p)\b`)
+)
+
+// New parses an offer from its Tesco promotion fields. Dates are RFC 3339 and left zero
+// when missing or invalid.
+func New(id, promotionType, start, end, text string) Offer {
+	o := Offer{ID: id, Type: promotionType, Text: strings.TrimSpace(text), Kind: Other, Quantity: 1}
+	o.Start, _ = time.Parse(time.RFC3339, start)
+	o.End, _ = time.Parse(time.RFC3339, end)
+
+	rest := o.Text
+	if clubcardText.MatchString(rest) || strings.Contains(strings.ToLower(promotionType), "clubcard") {
+		o.Clubcard = true
+		rest = strings.TrimSpace(clubcardText.ReplaceAllString(rest, " "))
+	}
+	if match := multiPrice.FindStringSubmatch(rest); match != nil {
+		o.Kind, o.Quantity, o.Price = MultiBuy, atoi(match[1]), money(match[2])
+	} else if match := multiCount.FindStringSubmatch(rest); match != nil {
+		o.Kind, o.Quantity, o.Pay = MultiBuy, atoi(match[1]), atoi(match[2])
+	} else if match := buyGetFree.FindStringSubmatch(rest); match != nil {
+		o.Kind, o.Pay = MultiBuy, atoi(match[1])
+		o.Quantity = o.Pay + atoi(match[2])
+	} else if halfPrice.MatchString(rest) {
+		o.Kind, o.Percent = PercentOff, 50
+	} else if match := percentOff.FindStringSubmatch(rest); match != nil {
+		o.Kind = PercentOff
+		o.Percent, _ = strconv.ParseFloat(match[1], 64)
+	} else if match := nowPrice.FindStringSubmatch(rest); match != nil {
+		o.Kind, o.Price = FixedPrice, money(match[1])
+		if o.Clubcard {
+			o.Kind = Clubcard
+		}
+	}
+	if o.Quantity <= 0 || (o.Kind == MultiBuy && o.Pay >= o.Quantity && o.Price == 0) {
+		o.Kind, o.Quantity, o.Pay = Other, 1, 0
+	}
+	return o
+}
+
+// FromProduct returns the offers on p
+func FromProduct(p *product.Product) []Offer {
+	offers := []Offer{}
+	for _, promotion := range gjson.Get(p.Raw(), "promotions").Array() {
+		offers = append(offers, New(
+			promotion.Get("promotionId").String(),
+			promotion.Get("promotionType").String(),
+			promotion.Get("startDate").String(),
+			promotion.Get("endDate").String(),
+			promotion.Get("offerText").String(),
+		))
+	}
+	return offers
+}
+
+// Active reports whether the offer runs at t. Offers without dates are always active.
+func (o Offer) Active(t time.Time) bool {
+	return (o.Start.IsZero() || !t.Before(o.Start)) && (o.End.IsZero() || !t.After(o.End))
+}
+
+// EffectivePrice returns the price of one item when buying enough to get the offer, given
+// the shelf price, or false if the offer is not understood
+func (o Offer) EffectivePrice(shelfPrice float64) (float64, bool) {
+	switch o.Kind {
+	case FixedPrice, Clubcard:
+		return o.Price, true
+	case MultiBuy:
+		if o.Price > 0 {
+			return o.Price / float64(o.Quantity), true
+		}
+		if shelfPrice > 0 {
+			return shelfPrice * float64(o.Pay) / float64(o.Quantity), true
+		}
+	case PercentOff:
+		if shelfPrice > 0 {
+			return shelfPrice * (1 - o.Percent/100), true
+		}
+	}
+	return 0, false
+}
+
+// EffectiveUnitPrice returns the unit price, in the product's unit of measure, with the
+// offer applied, or false if the offer or prices are not known
+func (o Offer) EffectiveUnitPrice(shelfPrice, unitPrice float64) (float64, bool) {
+	price, ok := o.EffectivePrice(shelfPrice)
+	if !ok || shelfPrice <= 0 || unitPrice <= 0 {
+		return 0, false
+	}
+	return unitPrice * price / shelfPrice, true
+}
+
+// Cost returns the price of quantity items with the offer applied, given the shelf price,
+// or false if the offer is not understood. Multibuys apply to each full group of Quantity
+// items, with the rest at the shelf price.
+func (o Offer) Cost(shelfPrice float64, quantity int) (float64, bool) {
+	if o.Kind != MultiBuy {
+		price, ok := o.EffectivePrice(shelfPrice)
+		return price * float64(quantity), ok
+	}
+	if shelfPrice <= 0 && o.Price <= 0 {
+		return 0, false
+	}
+	group := o.Price
+	if group <= 0 {
+		group = shelfPrice * float64(o.Pay)
+	}
+	groups, rest := quantity/o.Quantity, quantity%o.Quantity
+	return float64(groups)*group + float64(rest)*shelfPrice, true
+}
+
+// ParseKind returns the kind named s, case insensitively
+func ParseKind(s string) (Kind, bool) {
+	for _, kind := range Kinds {
+		if strings.EqualFold(string(kind), strings.TrimSpace(s)) {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// money parses an amount such as £1.50 or 75p in pounds
+func money(s string) float64 {
+	if strings.HasSuffix(s, "p") {
+		pence, _ := strconv.ParseFloat(strings.TrimSuffix(s, "p"), 64)
+		return pence / 100
+	}
+	pounds, _ := strconv.ParseFloat(strings.TrimPrefix(s, "£"), 64)
+	return pounds
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
@@ -0,0 +1,126 @@
+package promotion
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		text     string
+		kind     Kind
+		clubcard bool
+		quantity int
+		price    float64
+		pay      int
+		percent  float64
+	}{
+		{"Any 2 for £1.00", MultiBuy, false, 2, 1, 0, 0},
+		{"3 for £10", MultiBuy, false, 3, 10, 0, 0},
+		{"2 for 75p", MultiBuy, false, 2, 0.75, 0, 0},
+		{"Any 3 for 2", MultiBuy, false, 3, 0, 2, 0},
+		{"Buy 1 get 1 free", MultiBuy, false, 2, 0, 1, 0},
+		{"£1.50 Clubcard Price", Clubcard, true, 1, 1.5, 0, 0},
+		{"Any 2 for £5 Clubcard Price", MultiBuy, true, 2, 5, 0, 0},
+		{"Was £3.00 Now £2.00", FixedPrice, false, 1, 2, 0, 0},
+		{"Save 25%", PercentOff, false, 1, 0, 0, 25},
+		{"Half Price", PercentOff, false, 1, 0, 0, 50},
+		{"Save £1.00", Other, false, 1, 0, 0, 0},
+		{"Free delivery", Other, false, 1, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			o := New("A1", "", "", "", tt.text)
+			if o.Kind != tt.kind || o.Clubcard != tt.clubcard || o.Quantity != tt.quantity || o.Price != tt.price || o.Pay != tt.pay || o.Percent != tt.percent {
+				t.Errorf("New() got = %+v", o)
+			}
+		})
+	}
+}
+
+func TestEffectivePrice(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantPrice float64
+		wantUnit  float64
+		wantOK    bool
+	}{
+		{"Any 2 for £3.00", 1.5, 7.5, true},
+		{"Any 3 for 2", 1.6, 8, true},
+		{"Save 25%", 1.8, 9, true},
+		{"£1.20 Clubcard Price", 1.2, 6, true},
+		{"Save £1.00", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			o := New("A1", "", "", "", tt.text)
+			price, ok := o.EffectivePrice(2.4)
+			if ok != tt.wantOK || math.Abs(price-tt.wantPrice) > 1e-9 {
+				t.Errorf("EffectivePrice() = %v, %v, want %v, %v", price, ok, tt.wantPrice, tt.wantOK)
+			}
+			unit, ok := o.EffectiveUnitPrice(2.4, 12)
+			if ok != tt.wantOK || math.Abs(unit-tt.wantUnit) > 1e-9 {
+				t.Errorf("EffectiveUnitPrice() = %v, %v, want %v, %v", unit, ok, tt.wantUnit, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		text     string
+		quantity int
+		want     float64
+		wantOK   bool
+	}{
+		{"Any 2 for £3.00", 5, 8.4, true},
+		{"Any 3 for 2", 3, 4.8, true},
+		{"Any 3 for 2", 2, 4.8, true},
+		{"Save 25%", 2, 3.6, true},
+		{"£1.20 Clubcard Price", 3, 3.6, true},
+		{"Save £1.00", 2, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := New("A1", "", "", "", tt.text).Cost(2.4, tt.quantity)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Cost() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestActive(t *testing.T) {
+	o := New("A32766558", "2for", "2019-11-20T00:00:00.000Z", "2019-12-30T00:00:00.000Z", "Any 2 for £1.00")
+	tests := map[string]bool{
+		"2019-11-19T23:59:59Z": false,
+		"2019-11-20T00:00:00Z": true,
+		"2019-12-01T12:00:00Z": true,
+		"2019-12-30T00:00:01Z": false,
+	}
+	for at, want := range tests {
+		ts, _ := time.Parse(time.RFC3339, at)
+		if got := o.Active(ts); got != want {
+			t.Errorf("Active(%v) = %v, want %v", at, got, want)
+		}
+	}
+	if !New("A1", "", "", "", "Half Price").Active(time.Now()) {
+		t.Error("Active() of an undated offer = false, want true")
+	}
+}
+
+func TestFromProduct(t *testing.T) {
+	p, err := product.NewProduct(`{"product":{"price":0.85},"promotions":[{"promotionId":"A32766558","promotionType":"2for",`+
+		`"startDate":"2019-11-20T00:00:00.000Z","endDate":"2019-12-30T00:00:00.000Z","offerText":"Any 2 for £1.00"}]}`,
+		product.IDToURL("258092039"))
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	offers := FromProduct(p)
+	if len(offers) != 1 || offers[0].ID != "A32766558" || offers[0].Type != "2for" || offers[0].Kind != MultiBuy || offers[0].End.IsZero() {
+		t.Errorf("FromProduct() got = %+v", offers)
+	}
+}
@@ -0,0 +1,355 @@
+// Package query parses a small filter language over stored products and compiles it to SQL.
+//
+// An expression combines comparisons and tags with AND, OR, NOT and parentheses:
+//
+//	protein>20 AND kcal<200 AND aisle="Yoghurts" AND NOT allergen:milk
+//
+// Comparisons are field op value, where op is one of = != < <= > >= or ~ (contains).
+// Text comparisons ignore case. A tag is kind:value and matches products carrying that tag.
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the type of a field
+type Kind int
+
+const (
+	Number Kind = iota
+	Text
+)
+
+// Field is a queryable column
+type Field struct {
+	Column string
+	Kind   Kind
+}
+
+// Fields maps field names to columns of the product_facts table
+var Fields = map[string]Field{
+	"name":           {"name", Text},
+	"brand":          {"brand", Text},
+	"department":     {"department", Text},
+	"aisle":          {"aisle", Text},
+	"shelf":          {"shelf", Text},
+	"price":          {"price", Number},
+	"unitprice":      {"unit_price", Number},
+	"price100":       {"price_per_100", Number},
+	"kcal":           {"kcal", Number},
+	"protein":        {"protein", Number},
+	"carbs":          {"carbs", Number},
+	"fat":            {"fat", Number},
+	"gtin":           {"gtin", Text},
+	"saturates":      {"saturates", Number},
+	"sugars":         {"sugars", Number},
+	"fibre":          {"fibre", Number},
+	"salt":           {"salt", Number},
+	"nutriscore":     {"nutri_score", Number},
+	"grade":          {"nutri_grade", Text},
+	"fatlight":       {"fat_light", Text},
+	"satlight":       {"saturates_light", Text},
+	"sugarlight":     {"sugars_light", Text},
+	"saltlight":      {"salt_light", Text},
+	"packsize":       {"pack_size", Number},
+	"servings":       {"servings", Number},
+	"packkcal":       {"pack_kcal", Number},
+	"packprotein":    {"pack_protein", Number},
+	"servingkcal":    {"serving_kcal", Number},
+	"servingprotein": {"serving_protein", Number},
+	"servingcost":    {"cost_per_serving", Number},
+	"rating":         {"rating", Number},
+	"reviews":        {"review_count", Number},
+}
+
+// FieldNames returns the names of Fields in alphabetical order
+func FieldNames() []string {
+	names := make([]string, 0, len(Fields))
+	for name := range Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Compile parses expr and returns an SQL condition over product_facts, aliased as f,
+// with its arguments
+func Compile(expr string) (string, []interface{}, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.or()
+	if err != nil {
+		return "", nil, err
+	}
+	if t := p.peek(); t.kind != eof {
+		return "", nil, fmt.Errorf("unexpected %q at position %v", t.text, t.pos)
+	}
+	var args []interface{}
+	sql := node.sql(&args)
+	return sql, args, nil
+}
+
+// OrderBy returns an ORDER BY clause for field
+func OrderBy(field string, descending bool) (string, error) {
+	f, ok := Fields[strings.ToLower(field)]
+	if !ok {
+		return "", fmt.Errorf("unknown field %q, must be one of: %v", field, strings.Join(FieldNames(), ", "))
+	}
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	column := "f." + f.Column
+	// traffic lights sort by severity rather than alphabetically
+	if strings.HasSuffix(f.Column, "_light") {
+		column = fmt.Sprintf("CASE %v WHEN 'green' THEN 0 WHEN 'amber' THEN 1 WHEN 'red' THEN 2 END", column)
+	}
+	return fmt.Sprintf("ORDER BY f.%v IS NULL, %v %v", f.Column, column, direction), nil
+}
+
+type node interface {
+	sql(args *[]interface{}) string
+}
+
+type and struct{ left, right node }
+type or struct{ left, right node }
+type not struct{ operand node }
+
+type comparison struct {
+	field Field
+	op    string
+	value string
+}
+
+type tag struct{ kind, value string }
+
+func (n and) sql(args *[]interface{}) string {
+	return "(" + n.left.sql(args) + " AND " + n.right.sql(args) + ")"
+}
+
+func (n or) sql(args *[]interface{}) string {
+	return "(" + n.left.sql(args) + " OR " + n.right.sql(args) + ")"
+}
+
+func (n not) sql(args *[]interface{}) string {
+	return "NOT " + n.operand.sql(args)
+}
+
+func (n comparison) sql(args *[]interface{}) string {
+	column := "f." + n.field.Column
+	if n.field.Kind == Number {
+		f, _ := strconv.ParseFloat(n.value, 64)
+		*args = append(*args, f)
+		return fmt.Sprintf("%v %v ?", column, n.op)
+	}
+	if n.op == "~" {
+		*args = append(*args, "%"+likeEscaper.Replace(n.value)+"%")
+		return fmt.Sprintf(`%v LIKE ? ESCAPE '\'`, column)
+	}
+	*args = append(*args, n.value)
+	return fmt.Sprintf("%v %v ? COLLATE NOCASE", column, n.op)
+}
+
+// likeEscaper escapes the LIKE wildcards so that ~ matches them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (n tag) sql(args *[]interface{}) string {
+	*args = append(*args, strings.ToLower(n.kind+":"+n.value))
+	return "EXISTS (SELECT 1 FROM product_tags t WHERE t.product_id = f.product_id AND t.tag = ?)"
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != eof {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == ident && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// or = and { "OR" and }
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+// and = not { "AND" not }
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+	return left, nil
+}
+
+// not = "NOT" not | primary
+func (p *parser) not() (node, error) {
+	if p.keyword("NOT") {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return not{operand}, nil
+	}
+	return p.primary()
+}
+
+// primary = "(" or ")" | ident op value | ident ":" value
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case lparen:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != rparen {
+			return nil, fmt.Errorf("expected ) at position %v", closing.pos)
+		}
+		return n, nil
+	case ident:
+	case eof:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %v", t.text, t.pos)
+	}
+
+	op := p.next()
+	if op.kind != operator {
+		return nil, fmt.Errorf("expected an operator after %q at position %v", t.text, op.pos)
+	}
+	value := p.next()
+	if value.kind != ident && value.kind != str && value.kind != num {
+		return nil, fmt.Errorf("expected a value after %q at position %v", op.text, value.pos)
+	}
+
+	if op.text == ":" {
+		return tag{kind: t.text, value: value.text}, nil
+	}
+
+	field, ok := Fields[strings.ToLower(t.text)]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q, must be one of: %v", t.text, strings.Join(FieldNames(), ", "))
+	}
+	if field.Kind == Number {
+		if value.kind != num {
+			return nil, fmt.Errorf("%v is numeric but %q is not a number", t.text, value.text)
+		}
+		if op.text == "~" {
+			return nil, fmt.Errorf("~ cannot be used with numeric field %v", t.text)
+		}
+	} else if op.text != "=" && op.text != "!=" && op.text != "~" {
+		return nil, fmt.Errorf("%v is text and can only be compared with =, != or ~", t.text)
+	}
+	return comparison{field: field, op: op.text, value: value.text}, nil
+}
+
+type tokenKind int
+
+const (
+	eof tokenKind = iota
+	ident
+	num
+	str
+	operator
+	lparen
+	rparen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func lex(s string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{lparen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{rparen, ")", i})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %v", i)
+			}
+			tokens = append(tokens, token{str, s[i+1 : i+1+end], i})
+			i += end + 2
+		case strings.IndexByte("=!<>~:", c) >= 0:
+			op := string(c)
+			if i+1 < len(s) && s[i+1] == '=' && c != '=' && c != '~' && c != ':' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("expected != at position %v", i)
+			}
+			tokens = append(tokens, token{operator, op, i})
+			i += len(op)
+		case isIdentChar(c):
+			start := i
+			for i < len(s) && isIdentChar(s[i]) {
+				i++
+			}
+			text := s[start:i]
+			kind := ident
+			if _, err := strconv.ParseFloat(text, 64); err == nil {
+				kind = num
+			}
+			tokens = append(tokens, token{kind, text, start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %v", c, i)
+		}
+	}
+	return append(tokens, token{eof, "", len(s)}), nil
+}
@@ -0,0 +1,91 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		expr     string
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			"protein>20",
+			"f.protein > ?",
+			[]interface{}{20.0},
+			false,
+		},
+		{
+			`protein>20 AND kcal<200 AND aisle="Yoghurts" AND NOT allergen:milk`,
+			"(((f.protein > ? AND f.kcal < ?) AND f.aisle = ? COLLATE NOCASE) AND NOT EXISTS (SELECT 1 FROM product_tags t WHERE t.product_id = f.product_id AND t.tag = ?))",
+			[]interface{}{20.0, 200.0, "Yoghurts", "allergen:milk"},
+			false,
+		},
+		{
+			"brand=tesco or (fat <= 3.5 and name ~ 'greek')",
+			`(f.brand = ? COLLATE NOCASE OR (f.fat <= ? AND f.name LIKE ? ESCAPE '\'))`,
+			[]interface{}{"tesco", 3.5, "%greek%"},
+			false,
+		},
+		{
+			`name ~ "50%_off"`,
+			`f.name LIKE ? ESCAPE '\'`,
+			[]interface{}{`%50\%\_off%`},
+			false,
+		},
+		{
+			"price != 1",
+			"f.price != ?",
+			[]interface{}{1.0},
+			false,
+		},
+		{"sugar>1", "", nil, true},
+		{"protein>lots", "", nil, true},
+		{"name<3", "", nil, true},
+		{"protein>20 AND", "", nil, true},
+		{"(protein>20", "", nil, true},
+		{`aisle="Yoghurts`, "", nil, true},
+		{"protein 20", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			sql, args, err := Compile(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("Compile() sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Compile() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		field      string
+		descending bool
+		want       string
+		wantErr    bool
+	}{
+		{"protein", true, "ORDER BY f.protein IS NULL, f.protein DESC", false},
+		{"saltlight", false, "ORDER BY f.salt_light IS NULL, CASE f.salt_light WHEN 'green' THEN 0 WHEN 'amber' THEN 1 WHEN 'red' THEN 2 END ASC", false},
+		{"sugar", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := OrderBy(tt.field, tt.descending)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OrderBy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("OrderBy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package rank
+
+import (
+	"regexp"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+// densities are approximate densities in g/ml of common liquids, matched in order against
+// product names so more specific names come first
+var densities = []struct {
+	name    *regexp.Regexp
+	density float64
+}{
+	{regexp.MustCompile(`(?i)\bcondensed milk\b`), 1.3},
+	{regexp.MustCompile(`(?i)\bhoney\b`), 1.42},
+	{regexp.MustCompile(`(?i)\b(syrup|treacle)\b`), 1.33},
+	{regexp.MustCompile(`(?i)\boil\b`), 0.92},
+	{regexp.MustCompile(`(?i)\bcream\b`), 1.0},
+	{regexp.MustCompile(`(?i)\b(milk|milkshake|kefir)\b`), 1.03},
+	{regexp.MustCompile(`(?i)\b(smoothie|juice)\b`), 1.05},
+	{regexp.MustCompile(`(?i)\b(vodka|gin|rum|whisky|whiskey|brandy)\b`), 0.95},
+	{regexp.MustCompile(`(?i)\b(wine|beer|lager|cider|ale)\b`), 1.0},
+	{regexp.MustCompile(`(?i)\b(vinegar|soy sauce)\b`), 1.05},
+	{regexp.MustCompile(`(?i)\b(water|cola|lemonade|squash|drink)\b`), 1.0},
+}
+
+// Density returns the approximate density in g/ml of a product whose nutrition is given
+// per volume, if it is a known kind of liquid
+func Density(p *product.Product) (float64, bool) {
+	if !p.PerComp().IsLiquid() {
+		return 0, false
+	}
+	for _, d := range densities {
+		if d.name.MatchString(p.Name()) {
+			return d.density, true
+		}
+	}
+	return 0, false
+}
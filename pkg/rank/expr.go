@@ -0,0 +1,175 @@
+package rank
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is an arithmetic expression over product variables, e.g. protein/kcal
+type Expr interface {
+	// Eval returns the value of the expression, or false if a variable is unknown
+	// or the expression divides by zero
+	Eval(vars map[string]float64) (float64, bool)
+	String() string
+}
+
+type number float64
+
+func (n number) Eval(map[string]float64) (float64, bool) { return float64(n), true }
+func (n number) String() string                          { return strconv.FormatFloat(float64(n), 'f', -1, 64) }
+
+type variable string
+
+func (v variable) Eval(vars map[string]float64) (float64, bool) {
+	f, ok := vars[string(v)]
+	return f, ok
+}
+func (v variable) String() string { return string(v) }
+
+type binary struct {
+	op          byte
+	left, right Expr
+}
+
+func (b binary) Eval(vars map[string]float64) (float64, bool) {
+	l, ok := b.left.Eval(vars)
+	if !ok {
+		return 0, false
+	}
+	r, ok := b.right.Eval(vars)
+	if !ok {
+		return 0, false
+	}
+	switch b.op {
+	case '+':
+		return l + r, true
+	case '-':
+		return l - r, true
+	case '*':
+		return l * r, true
+	case '/':
+		if r == 0 {
+			return 0, false
+		}
+		return l / r, true
+	}
+	return 0, false
+}
+
+func (b binary) String() string {
+	return fmt.Sprintf("(%v%c%v)", b.left, b.op, b.right)
+}
+
+// Parse parses an expression of numbers, variables, + - * / and parentheses.
+// Variable names must be in known.
+func Parse(s string, known []string) (Expr, error) {
+	p := &parser{input: s, known: known}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at position %v", p.input[p.pos:], p.pos)
+	}
+	return e, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+	known []string
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// expr = term { ("+" | "-") term }
+func (p *parser) expr() (Expr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// term = factor { ("*" | "/") factor }
+func (p *parser) term() (Expr, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// factor = number | variable | "(" expr ")"
+func (p *parser) factor() (Expr, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at position %v", p.pos)
+		}
+		p.pos++
+		return e, nil
+	case c == '.' || unicode.IsDigit(rune(c)):
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] == '.' || unicode.IsDigit(rune(p.input[p.pos]))) {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+		}
+		return number(f), nil
+	case unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] == '_' || unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+			p.pos++
+		}
+		name := strings.ToLower(p.input[start:p.pos])
+		for _, k := range p.known {
+			if k == name {
+				return variable(name), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown variable %q, must be one of: %v", name, strings.Join(p.known, ", "))
+	}
+	return nil, fmt.Errorf("unexpected %q at position %v", c, p.pos)
+}
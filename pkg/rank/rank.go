@@ -0,0 +1,175 @@
+// Package rank orders products by a metric computed from their macros and price
+package rank
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+// Variables lists the names usable in a metric expression. Nutrients are per 100g, or
+// 100ml for liquids, and price is pounds per 100g or 100ml derived from the unit price, so
+// protein/price is grams of protein per pound.
+var Variables = []string{"kcal", "protein", "carbs", "fat", "price"}
+
+// Vars returns the values of Variables for p. Nutrients p does not give are omitted, as is
+// price when the unit price cannot be converted to pounds per 100g or 100ml. When byWeight
+// is set, liquids with a known Density are converted to per 100g so they compare with solids.
+func Vars(p *product.Product, byWeight bool) map[string]float64 {
+	perComp := p.PerComp()
+	// nutrition is usually given per 100, but scale it when it is not
+	scale := 1.0
+	if perComp.Size() > 0 {
+		scale = 100 / perComp.Size()
+	}
+	density, convert := 1.0, false
+	if byWeight {
+		density, convert = Density(p)
+		if !convert {
+			density = 1
+		}
+	}
+	vars := map[string]float64{}
+	add := func(name string, n product.Nutrient, value float64) {
+		if perComp.Has(n) {
+			vars[name] = value * scale / density
+		}
+	}
+	add("kcal", product.Energy, perComp.Kcal())
+	add("protein", product.Protein, perComp.Protein())
+	add("carbs", product.Carbs, perComp.Carbs())
+	add("fat", product.Fat, perComp.Fat())
+	if price, ok := PricePer100(p.UnitPrice(), p.UnitOfMeasure()); ok {
+		if convert && IsVolume(p.UnitOfMeasure()) {
+			price /= density
+		}
+		vars["price"] = price
+	}
+	return vars
+}
+
+// IsVolume reports whether a unit of measure such as litre is a volume rather than a weight
+func IsVolume(unitOfMeasure string) bool {
+	switch strings.ToLower(unitOfMeasure) {
+	case "litre", "l", "ltr", "100ml", "ml", "cl":
+		return true
+	}
+	return false
+}
+
+// PricePer100 converts a unit price to pounds per 100g or 100ml
+func PricePer100(unitPrice float64, unitOfMeasure string) (float64, bool) {
+	if unitPrice <= 0 {
+		return 0, false
+	}
+	switch strings.ToLower(unitOfMeasure) {
+	case "kg", "litre", "l", "ltr":
+		return unitPrice / 10, true
+	case "100g", "100ml":
+		return unitPrice, true
+	case "cl":
+		return unitPrice * 10, true
+	case "g", "ml":
+		return unitPrice * 100, true
+	}
+	return 0, false
+}
+
+// Threshold bounds a variable, e.g. protein >= 20
+type Threshold struct {
+	Variable string
+	Min      bool
+	Value    float64
+}
+
+// Match reports whether vars satisfies t. Unknown variables never match.
+func (t Threshold) Match(vars map[string]float64) bool {
+	v, ok := vars[t.Variable]
+	if !ok {
+		return false
+	}
+	if t.Min {
+		return v >= t.Value
+	}
+	return v <= t.Value
+}
+
+// NewThresholds builds thresholds from maps of variable name to minimum and maximum
+func NewThresholds(min, max map[string]float64) ([]Threshold, error) {
+	thresholds := []Threshold{}
+	add := func(bounds map[string]float64, isMin bool) error {
+		names := make([]string, 0, len(bounds))
+		for name := range bounds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			variable := strings.ToLower(name)
+			if !known(variable) {
+				return fmt.Errorf("unknown variable %q, must be one of: %v", name, strings.Join(Variables, ", "))
+			}
+			thresholds = append(thresholds, Threshold{Variable: variable, Min: isMin, Value: bounds[name]})
+		}
+		return nil
+	}
+	if err := add(min, true); err != nil {
+		return nil, err
+	}
+	if err := add(max, false); err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+func known(name string) bool {
+	for _, v := range Variables {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is a product and its metric value
+type Result struct {
+	Product *product.Product
+	Value   float64
+}
+
+// Rank evaluates metric for each product passing thresholds and returns the best limit
+// results, highest first unless ascending. Products for which the metric is undefined
+// are left out. A limit of 0 or less returns every result. byWeight is passed to Vars.
+func Rank(products []*product.Product, metric Expr, thresholds []Threshold, limit int, ascending bool, byWeight bool) []Result {
+	results := []Result{}
+	for _, p := range products {
+		vars := Vars(p, byWeight)
+		passes := true
+		for _, t := range thresholds {
+			if !t.Match(vars) {
+				passes = false
+				break
+			}
+		}
+		if !passes {
+			continue
+		}
+		value, ok := metric.Eval(vars)
+		if !ok {
+			continue
+		}
+		results = append(results, Result{Product: p, Value: value})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if ascending {
+			return results[i].Value < results[j].Value
+		}
+		return results[i].Value > results[j].Value
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,175 @@
+package rank
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestParse(t *testing.T) {
+	vars := map[string]float64{"protein": 20, "kcal": 200, "fat": 10, "price": 0.5}
+	tests := []struct {
+		expr    string
+		want    float64
+		wantOK  bool
+		wantErr bool
+	}{
+		{"protein/kcal", 0.1, true, false},
+		{"protein / price", 40, true, false},
+		{"100 * protein / kcal", 10, true, false},
+		{"(protein + fat) * 2", 60, true, false},
+		{"protein - fat - 5", 5, true, false},
+		{"fat/carbs", 0, false, false},
+		{"protein/0", 0, false, false},
+		{"Protein/KCAL", 0.1, true, false},
+		{"protein/sugar", 0, false, true},
+		{"protein/", 0, false, true},
+		{"(protein", 0, false, true},
+		{"protein kcal", 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr, []string{"kcal", "protein", "carbs", "fat", "price"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, ok := e.Eval(vars)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Eval() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		id  string
+		raw string
+	}{
+		{"100000001", `{"pageTitle":"Rump Steak","product":{"unitPrice":13.93,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 171kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"10g","perServing":"-"},{"name":"Protein","perComp":"20.3g","perServing":"-"}]}}}`},
+		{"100000002", `{"pageTitle":"Chicken Breast","product":{"unitPrice":8.00,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 98kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"1.1g","perServing":"-"},{"name":"Protein","perComp":"23g","perServing":"-"}]}}}`},
+		{"100000003", `{"pageTitle":"Red Lentils","product":{"unitPrice":2.50,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 120kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"0.4g","perServing":"-"},{"name":"Protein","perComp":"10g","perServing":"-"}]}}}`},
+		{"100000004", `{"pageTitle":"Still Water","product":{"unitPrice":1.00,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"0kJ / 0kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"0g","perServing":"-"},{"name":"Protein","perComp":"0g","perServing":"-"}]}}}`},
+		// no nutrition at all, so it never passes a nutrient threshold
+		{"100000005", `{"pageTitle":"Mystery Box","product":{"unitPrice":0.10,"unitOfMeasure":"kg"}}`},
+	}
+	products := []*product.Product{}
+	for _, tt := range tests {
+		p, err := product.NewProduct(tt.raw, product.IDToURL(tt.id))
+		if err != nil {
+			t.Fatalf("NewProduct() error = %v", err)
+		}
+		products = append(products, p)
+	}
+	protein, err := Parse("protein/price", Variables)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	price, err := Parse("price", Variables)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	ranks := []struct {
+		name      string
+		metric    Expr
+		min, max  map[string]float64
+		limit     int
+		ascending bool
+		want      []string
+		wantValue float64
+	}{
+		{"limit", protein, nil, nil, 2, false, []string{"100000003", "100000002"}, 40},
+		{"thresholds", protein, map[string]float64{"protein": 15}, map[string]float64{"kcal": 150}, 0, false, []string{"100000002"}, 28.75},
+		{"no metric without nutrition", price, nil, nil, 1, true, []string{"100000005"}, 0.01},
+		{"max leaves out unknown nutrients", price, nil, map[string]float64{"fat": 5}, 0, true, []string{"100000004", "100000003", "100000002"}, 0.1},
+	}
+	for _, tt := range ranks {
+		t.Run(tt.name, func(t *testing.T) {
+			thresholds, err := NewThresholds(tt.min, tt.max)
+			if err != nil {
+				t.Fatalf("NewThresholds() error = %v", err)
+			}
+			got := Rank(products, tt.metric, thresholds, tt.limit, tt.ascending, false)
+			ids := []string{}
+			for _, r := range got {
+				ids = append(ids, r.Product.ID())
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Fatalf("Rank() = %v, want %v", ids, tt.want)
+			}
+			if math.Abs(got[0].Value-tt.wantValue) > 1e-9 {
+				t.Errorf("Rank()[0].Value = %v, want %v", got[0].Value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestVars(t *testing.T) {
+	oil := `{"pageTitle":"Tesco Vegetable Oil 1L","product":{"unitPrice":1.84,"unitOfMeasure":"litre","details":{"nutritionInfo":[` +
+		`{"name":"Typical Values","perComp":"Per 100ml","perServing":"-"},` +
+		`{"name":"Energy","perComp":"3404kJ / 828kcal","perServing":"-"},` +
+		`{"name":"Fat","perComp":"92g","perServing":"-"}]}}}`
+	tests := []struct {
+		name      string
+		raw       string
+		byWeight  bool
+		wantKcal  float64
+		wantPrice float64
+	}{
+		{"per 100ml", oil, false, 828, 0.184},
+		{"per 100g by density", oil, true, 900, 0.2},
+		{
+			"per 0.1l",
+			`{"pageTitle":"Tesco Vegetable Oil 1L","product":{"unitPrice":1.84,"unitOfMeasure":"litre","details":{"nutritionInfo":[` +
+				`{"name":"Typical Values","perComp":"Per 0.1l","perServing":"-"},` +
+				`{"name":"Energy","perComp":"3404kJ / 828kcal","perServing":"-"}]}}}`,
+			false, 828, 0.184,
+		},
+		{
+			"unknown density",
+			`{"pageTitle":"Tesco Mystery Liquid 1L","product":{"unitPrice":1.84,"unitOfMeasure":"litre","details":{"nutritionInfo":[` +
+				`{"name":"Typical Values","perComp":"Per 100ml","perServing":"-"},` +
+				`{"name":"Energy","perComp":"3404kJ / 828kcal","perServing":"-"}]}}}`,
+			true, 828, 0.184,
+		},
+		{
+			"solid by weight",
+			`{"pageTitle":"Tesco Lard 250G","product":{"unitPrice":1.84,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+				`{"name":"Typical Values","perComp":"Per 50g","perServing":"-"},` +
+				`{"name":"Energy","perComp":"3404kJ / 828kcal","perServing":"-"}]}}}`,
+			true, 1656, 0.184,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := product.NewProduct(tt.raw, product.IDToURL("100000001"))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			vars := Vars(p, tt.byWeight)
+			if math.Abs(vars["kcal"]-tt.wantKcal) > 1e-9 {
+				t.Errorf("Vars()[kcal] = %v, want %v", vars["kcal"], tt.wantKcal)
+			}
+			if math.Abs(vars["price"]-tt.wantPrice) > 1e-9 {
+				t.Errorf("Vars()[price] = %v, want %v", vars["price"], tt.wantPrice)
+			}
+			if _, ok := vars["protein"]; ok {
+				t.Errorf("Vars()[protein] is set for a product that does not give it")
+			}
+		})
+	}
+}
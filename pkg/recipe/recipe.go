@@ -0,0 +1,184 @@
+// Package recipe totals the nutrition and cost of a recipe from the products in it
+package recipe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/mattburman/tesco/pkg/nutrition"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/rank"
+	"gopkg.in/yaml.v2"
+)
+
+// Ingredient is an amount of a product, given by its ID or a search term, in grams or ml
+type Ingredient struct {
+	Product string  `yaml:"product" json:"product"`
+	Grams   float64 `yaml:"grams,omitempty" json:"grams,omitempty"`
+	ML      float64 `yaml:"ml,omitempty" json:"ml,omitempty"`
+}
+
+// Recipe is a list of ingredients made into a number of portions
+type Recipe struct {
+	Name        string       `yaml:"name" json:"name"`
+	Portions    int          `yaml:"portions" json:"portions"`
+	Ingredients []Ingredient `yaml:"ingredients" json:"ingredients"`
+}
+
+// Parse reads a recipe from YAML, e.g.
+//
+//	name: Chilli
+//	portions: 4
+//	ingredients:
+//	  - product: "254918073"
+//	    grams: 500
+//	  - product: kidney beans
+//	    grams: 400
+func Parse(r io.Reader) (Recipe, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Recipe{}, err
+	}
+	var recipe Recipe
+	if err := yaml.UnmarshalStrict(data, &recipe); err != nil {
+		return Recipe{}, fmt.Errorf("invalid recipe: %v", err)
+	}
+	if len(recipe.Ingredients) == 0 {
+		return Recipe{}, errors.New("recipe has no ingredients")
+	}
+	if recipe.Portions <= 0 {
+		recipe.Portions = 1
+	}
+	for i, ingredient := range recipe.Ingredients {
+		if strings.TrimSpace(ingredient.Product) == "" {
+			return Recipe{}, fmt.Errorf("ingredient %v has no product", i+1)
+		}
+		if (ingredient.Grams > 0) == (ingredient.ML > 0) || ingredient.Grams < 0 || ingredient.ML < 0 {
+			return Recipe{}, fmt.Errorf("ingredient %v (%v) needs either grams or ml", i+1, ingredient.Product)
+		}
+	}
+	return recipe, nil
+}
+
+// Amount returns the amount of the ingredient and its unit, "g" or "ml"
+func (i Ingredient) Amount() (float64, string) {
+	if i.ML > 0 {
+		return i.ML, "ml"
+	}
+	return i.Grams, "g"
+}
+
+// Resolver finds the product for an ingredient's ID or search term
+type Resolver func(ref string) (*product.Product, error)
+
+// Line is the nutrition and cost of an ingredient
+type Line struct {
+	Ingredient string  `json:"ingredient"`
+	ID         string  `json:"id,omitempty"`
+	Name       string  `json:"name,omitempty"`
+	Amount     float64 `json:"amount"`
+	Unit       string  `json:"unit"`
+	// Nutrients and Cost are nil when the product's nutrition or price is unknown
+	Nutrients *nutrition.Nutrients `json:"nutrients"`
+	Cost      *float64             `json:"cost"`
+}
+
+// Analysis is the nutrition and cost of a recipe in total and per portion. Totals leave out
+// ingredients with unknown nutrition or price, which are listed in Warnings.
+type Analysis struct {
+	Name           string              `json:"name"`
+	Portions       int                 `json:"portions"`
+	Lines          []Line              `json:"lines"`
+	Total          nutrition.Nutrients `json:"total"`
+	PerPortion     nutrition.Nutrients `json:"perPortion"`
+	Cost           float64             `json:"cost"`
+	CostPerPortion float64             `json:"costPerPortion"`
+	Warnings       []string            `json:"warnings"`
+}
+
+// Analyze resolves each ingredient of r and totals their nutrition, from the products'
+// values per 100g or 100ml, and cost, from their unit prices
+func Analyze(r Recipe, resolve Resolver) (Analysis, error) {
+	a := Analysis{Name: r.Name, Portions: r.Portions, Lines: []Line{}, Warnings: []string{}}
+	if a.Portions <= 0 {
+		a.Portions = 1
+	}
+	for _, ingredient := range r.Ingredients {
+		p, err := resolve(ingredient.Product)
+		if err != nil {
+			return Analysis{}, fmt.Errorf("unable to find %q: %v", ingredient.Product, err)
+		}
+		amount, unit := ingredient.Amount()
+		line := Line{Ingredient: ingredient.Product, ID: p.ID(), Name: p.Name(), Amount: amount, Unit: unit}
+
+		comp := p.PerComp()
+		if compAmount, ok := convert(p, amount, unit, comp.Unit()); ok && comp.HasAny() {
+			if scaled, ok := comp.Scale(compAmount); ok {
+				nutrients := nutrition.FromMacros(scaled)
+				line.Nutrients = &nutrients
+				a.Total = a.Total.Add(nutrients, 1)
+			}
+		}
+		if line.Nutrients == nil {
+			a.Warnings = append(a.Warnings, fmt.Sprintf("%v (%v) has no nutrition per 100%v, left out of the totals", p.Name(), p.ID(), unit))
+		}
+
+		if cost, ok := cost(p, amount, unit); ok {
+			line.Cost = &cost
+			a.Cost += cost
+		} else {
+			a.Warnings = append(a.Warnings, fmt.Sprintf("%v (%v) has no price per %v, left out of the cost", p.Name(), p.ID(), unit))
+		}
+		a.Lines = append(a.Lines, line)
+	}
+	a.PerPortion = nutrition.Nutrients{}.Add(a.Total, 1/float64(a.Portions))
+	a.CostPerPortion = a.Cost / float64(a.Portions)
+	return a, nil
+}
+
+// cost returns the price of amount of p from its unit price, or else its shelf price and
+// pack size
+func cost(p *product.Product, amount float64, unit string) (float64, bool) {
+	if per100, ok := rank.PricePer100(p.UnitPrice(), p.UnitOfMeasure()); ok {
+		if converted, ok := convert(p, amount, unit, measureUnit(p.UnitOfMeasure())); ok {
+			return per100 * converted / 100, true
+		}
+	}
+	pack := p.Pack()
+	if pack.Size() > 0 && p.Price() > 0 {
+		if converted, ok := convert(p, amount, unit, pack.Unit()); ok {
+			return p.Price() * converted / pack.Size(), true
+		}
+	}
+	return 0, false
+}
+
+// convert returns amount in unit as an amount in to, converting between grams and ml with
+// the density of known liquids
+func convert(p *product.Product, amount float64, unit, to string) (float64, bool) {
+	if to == unit {
+		return amount, true
+	}
+	density, ok := rank.Density(p)
+	if !ok || to == "" {
+		return 0, false
+	}
+	if unit == "ml" {
+		return amount * density, true
+	}
+	return amount / density, true
+}
+
+// measureUnit returns "g" or "ml" for a unit of measure such as kg or litre, or empty
+func measureUnit(unitOfMeasure string) string {
+	switch strings.ToLower(unitOfMeasure) {
+	case "kg", "g", "100g":
+		return "g"
+	case "litre", "l", "ltr", "ml", "100ml", "cl":
+		return "ml"
+	}
+	return ""
+}
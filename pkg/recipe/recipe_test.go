@@ -0,0 +1,83 @@
+package recipe
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"valid", "name: Chilli\nportions: 4\ningredients:\n  - product: \"100000001\"\n    grams: 500\n  - product: still water\n    ml: 250\n", false},
+		{"no ingredients", "name: Chilli\n", true},
+		{"no amount", "ingredients:\n  - product: \"100000001\"\n", true},
+		{"grams and ml", "ingredients:\n  - product: \"100000001\"\n    grams: 5\n    ml: 5\n", true},
+		{"unknown field", "ingredients:\n  - product: \"100000001\"\n    ounces: 5\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.yaml))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (got.Portions != 4 || len(got.Ingredients) != 2 || got.Ingredients[1].ML != 250) {
+				t.Errorf("Parse() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	products := map[string]string{
+		"100000001": `{"pageTitle":"Tesco Chicken Breast 650G","product":{"id":"100000001","title":"Tesco Chicken Breast 650G","price":3.9,"unitPrice":6,"unitOfMeasure":"kg","details":{` +
+			`"packSize":[{"value":"650","units":"g"}],"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},` +
+			`{"name":"Energy","perComp":"440kJ / 104kcal","perServing":"-"},` +
+			`{"name":"Protein","perComp":"24.0g","perServing":"-"}]}}}`,
+		"100000002": `{"pageTitle":"Still Water 2L","product":{"id":"100000002","title":"Still Water 2L","price":0.5,"unitPrice":0.25,"unitOfMeasure":"litre"}}`,
+		// no energy is still nutrition, unlike the water above
+		"100000003": `{"pageTitle":"Tesco Diet Cola 2L","product":{"id":"100000003","title":"Tesco Diet Cola 2L","price":2,"unitPrice":1,"unitOfMeasure":"litre","details":{` +
+			`"nutritionInfo":[{"name":"Typical Values","perComp":"Per 100ml","perServing":"-"},` +
+			`{"name":"Energy","perComp":"1kJ / 0kcal","perServing":"-"},` +
+			`{"name":"Sugars","perComp":"0g","perServing":"-"}]}}}`,
+	}
+	resolve := func(ref string) (*product.Product, error) {
+		if ref == "still water" {
+			ref = "100000002"
+		}
+		raw, ok := products[ref]
+		if !ok {
+			return nil, fmt.Errorf("no product %v", ref)
+		}
+		return product.NewProduct(raw, product.IDToURL(ref))
+	}
+	r := Recipe{Name: "Poached chicken", Portions: 4, Ingredients: []Ingredient{{Product: "100000001", Grams: 500}, {Product: "still water", ML: 1000}, {Product: "100000003", ML: 330}}}
+
+	got, err := Analyze(r, resolve)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if nutrients := fmt.Sprintf("%.0f %.0f %.0f", got.Total.Kcal, got.Total.Protein, got.PerPortion.Protein); nutrients != "520 120 30" {
+		t.Errorf("Analyze() nutrients = %+v per portion %+v", got.Total, got.PerPortion)
+	}
+	if cost := fmt.Sprintf("%.2f %.3f", got.Cost, got.CostPerPortion); cost != "3.58 0.895" {
+		t.Errorf("Analyze() cost = %v per portion %v, want 3.58 and 0.895", got.Cost, got.CostPerPortion)
+	}
+	if len(got.Lines) != 3 || got.Lines[0].ID != "100000001" || got.Lines[1].Nutrients != nil || got.Lines[1].Cost == nil || got.Lines[2].Nutrients == nil {
+		t.Errorf("Analyze() lines = %+v", got.Lines)
+	}
+	if len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], "Still Water 2L (100000002) has no nutrition") {
+		t.Errorf("Analyze() warnings = %v", got.Warnings)
+	}
+
+	r.Ingredients = append(r.Ingredients, Ingredient{Product: "unicorn", Grams: 1})
+	if _, err := Analyze(r, resolve); err == nil {
+		t.Errorf("Analyze() with an unknown product succeeded")
+	}
+}
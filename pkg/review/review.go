@@ -0,0 +1,82 @@
+// Package review parses the customer reviews and rating summary of a product
+package review
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/tidwall/gjson"
+)
+
+// Review is a customer review of a product
+type Review struct {
+	ID     string  `json:"id"`
+	Rating float64 `json:"rating"`
+	// Range is the highest rating possible, usually 5
+	Range     float64   `json:"range"`
+	Summary   string    `json:"summary"`
+	Text      string    `json:"text"`
+	Submitted time.Time `json:"submitted"`
+	// Source is the site a syndicated review was first posted on, or empty
+	Source string `json:"source,omitempty"`
+}
+
+// Stats is the rating summary of a product over all of its reviews, not only those fetched
+type Stats struct {
+	Rating float64 `json:"rating"`
+	Range  float64 `json:"range"`
+	Count  int     `json:"count"`
+	// Levels is the number of reviews giving each rating, when given
+	Levels map[int]int `json:"levels,omitempty"`
+}
+
+// FromProduct returns the reviews fetched with p, newest first as listed, and its rating
+// summary. Stats are false when p has no rating summary.
+func FromProduct(p *product.Product) ([]Review, Stats, bool) {
+	resource := gjson.Get(p.Raw(), "product.reviews")
+	reviews := []Review{}
+	for _, entry := range resource.Get("entries").Array() {
+		id := strings.TrimSpace(entry.Get("reviewId").String())
+		if id == "" {
+			continue
+		}
+		r := Review{
+			ID:      id,
+			Rating:  entry.Get("rating.value").Float(),
+			Range:   entry.Get("rating.range").Float(),
+			Summary: strings.TrimSpace(entry.Get("summary").String()),
+			Text:    strings.TrimSpace(entry.Get("text").String()),
+		}
+		if ms := entry.Get("submissionTime").Int(); ms > 0 {
+			r.Submitted = time.Unix(0, ms*int64(time.Millisecond)).UTC()
+		}
+		if entry.Get("syndicated").Bool() {
+			r.Source = entry.Get("syndicationSource.name").String()
+		}
+		reviews = append(reviews, r)
+	}
+
+	stats := resource.Get("stats")
+	if !stats.Get("noOfReviews").Exists() && !stats.Get("overallRating").Exists() {
+		return reviews, Stats{}, false
+	}
+	s := Stats{
+		Rating: stats.Get("overallRating").Float(),
+		Range:  stats.Get("overallRatingRange").Float(),
+		Count:  int(stats.Get("noOfReviews").Int()),
+	}
+	for _, level := range stats.Get("countsPerRatingLevel").Array() {
+		rating := level.Get("level")
+		if !rating.Exists() {
+			rating = level.Get("rating")
+		}
+		if rating.Int() > 0 {
+			if s.Levels == nil {
+				s.Levels = map[int]int{}
+			}
+			s.Levels[int(rating.Int())] = int(level.Get("count").Int())
+		}
+	}
+	return reviews, s, true
+}
@@ -0,0 +1,45 @@
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestFromProduct(t *testing.T) {
+	p, err := product.NewProduct(`{"product":{"price":3.55,"reviews":{"entries":[`+
+		`{"reviewId":"trn:1","rating":{"value":1,"range":5},"summary":"Fat and touch gristle","text":"Too much fat.","syndicated":false,"submissionTime":1572992864316},`+
+		`{"reviewId":"trn:2","rating":{"value":4,"range":5},"summary":" Tasty ","text":"Lovely.","syndicated":true,"syndicationSource":{"name":"tesco.com"},"submissionTime":1539178068000},`+
+		`{"reviewId":"","rating":{"value":5,"range":5}}],`+
+		`"stats":{"countsPerRatingLevel":[{"level":5,"count":3},{"level":1,"count":7}],"noOfReviews":10,"overallRatingRange":5,"overallRating":2.4}}}}`,
+		product.IDToURL("300400483"))
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	reviews, stats, ok := FromProduct(p)
+	if !ok || stats.Rating != 2.4 || stats.Range != 5 || stats.Count != 10 || stats.Levels[5] != 3 || stats.Levels[1] != 7 {
+		t.Errorf("FromProduct() stats = %+v, %v", stats, ok)
+	}
+	if len(reviews) != 2 {
+		t.Fatalf("FromProduct() got %v reviews, want 2", len(reviews))
+	}
+	if r := reviews[0]; r.ID != "trn:1" || r.Rating != 1 || r.Range != 5 || r.Source != "" ||
+		!r.Submitted.Equal(time.Date(2019, 11, 5, 22, 27, 44, 316000000, time.UTC)) {
+		t.Errorf("FromProduct() reviews[0] = %+v", r)
+	}
+	if r := reviews[1]; r.Summary != "Tasty" || r.Source != "tesco.com" {
+		t.Errorf("FromProduct() reviews[1] = %+v", r)
+	}
+}
+
+func TestFromProductWithoutReviews(t *testing.T) {
+	p, err := product.NewProduct(`{"product":{"price":3.55}}`, product.IDToURL("300400483"))
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	reviews, _, ok := FromProduct(p)
+	if ok || len(reviews) != 0 {
+		t.Errorf("FromProduct() = %v, %v", reviews, ok)
+	}
+}
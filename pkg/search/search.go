@@ -0,0 +1,199 @@
+// Package search implements functions to request and scrape Tesco groceries search results
+package search
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly"
+	"github.com/mattburman/tesco/pkg/category"
+	"github.com/mattburman/tesco/pkg/collecting"
+	"github.com/mattburman/tesco/pkg/gtin"
+	"github.com/mattburman/tesco/pkg/metrics"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/progress"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	searchURL = "https://www.tesco.com/groceries/en-GB/search"
+	// pageSize is the most results Tesco returns on a page
+	pageSize = 48
+)
+
+// URL returns the search results page URL for term. Pages are numbered from 1.
+func URL(term string, page int) string {
+	q := url.Values{}
+	q.Set("query", term)
+	q.Set("page", strconv.Itoa(page))
+	q.Set("count", strconv.Itoa(pageSize))
+	return searchURL + "?" + q.Encode()
+}
+
+// Get returns the products on up to pages pages of search results for term,
+// combined as {"results":{"productItems":[...]}}, or an error for network or request failures.
+// A pages value of 0 or less fetches every page.
+func Get(term string, pages int) (*string, error) {
+	items := []string{}
+	for page := 1; ; page++ {
+		data, err := getPage(term, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range gjson.Get(*data, "results.productItems").Array() {
+			items = append(items, item.Raw)
+		}
+		last := PageCount(*data)
+		if page >= last || (pages > 0 && page >= pages) {
+			break
+		}
+	}
+	s := `{"results":{"productItems":[` + strings.Join(items, ",") + `]}}`
+	return &s, nil
+}
+
+func getPage(term string, page int) (*string, error) {
+	resp, err := http.Get(URL(term, page))
+	if err != nil {
+		return nil, fmt.Errorf("request error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("request error: %v", err)
+	}
+
+	resources, err := product.ExtractResources(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract resources: %v", err)
+	}
+
+	data := gjson.Get(*resources, "search.data")
+	if !data.Exists() {
+		return nil, errors.New("unable to access search data in resources")
+	}
+	s := data.String()
+	return &s, nil
+}
+
+// ByGTIN searches Tesco for a normalised GTIN and returns the matching product's data,
+// checking the barcode of each product on the first page of results since searches
+// also match other numbers such as product IDs. Products which fail to fetch or parse are
+// reported on stderr and skipped. It returns nil if no product matches.
+func ByGTIN(normalized string) (*product.Product, *string, error) {
+	data, err := getPage(gtin.Short(normalized), 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range gjson.Get(*data, "results.productItems.#.product.id").Array() {
+		productData, err := product.GetProduct(id.String())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get product %v: %v\n", id, err)
+			continue
+		}
+		p, err := product.NewProduct(*productData, product.IDToURL(id.String()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to parse product %v: %v\n", id, err)
+			continue
+		}
+		if code, err := gtin.Normalize(p.GTIN()); err == nil && code == normalized {
+			return p, productData, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// PageCount returns the number of results pages from search data's page information,
+// which is 1 when it is missing
+func PageCount(data string) int {
+	info := gjson.Get(data, "results.pageInformation")
+	total, count := info.Get("totalCount").Float(), info.Get("count").Float()
+	if count <= 0 {
+		count = pageSize
+	}
+	if pages := int(math.Ceil(total / count)); pages > 1 {
+		return pages
+	}
+	return 1
+}
+
+// ToProductIDs takes search results resources JSON and returns extracted product IDs
+func ToProductIDs(resources *string) (*[]string, error) {
+	ids := gjson.Get(*resources, "search.data.results.productItems.#.product.id")
+	if !ids.Exists() {
+		return nil, fmt.Errorf("unable to extract product ids from search results")
+	}
+	idSlice := []string{}
+	for _, id := range ids.Array() {
+		idSlice = append(idSlice, id.String())
+	}
+	return &idSlice, nil
+}
+
+// Scrape visits up to pages pages of search results for term and places each product not
+// yet in the DB on productResults. A pages value of 0 or less visits every page.
+// Progress is recorded in stats, which may be nil. productResults is closed on return.
+func Scrape(term string, pages int, concurrency int, productResults chan category.ProductResult, db *sql.DB, stats *progress.Stats) error {
+	defer close(productResults)
+	if strings.TrimSpace(term) == "" {
+		return errors.New("empty search term")
+	}
+
+	productCollector := category.NewProductCollector(concurrency, productResults, stats)
+
+	searchCollector := colly.NewCollector(
+		colly.Async(true),
+	)
+	searchCollector.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: concurrency})
+	metrics.Instrument(searchCollector, "search")
+	searchCollector.OnResponse(func(r *colly.Response) {
+		stats.AddPage()
+	})
+	searchCollector.OnHTML("[data-props]", func(e *colly.HTMLElement) {
+		resources, err := collecting.ExtractResources(e.Attr("data-props"))
+		if err != nil {
+			stats.AddFailed()
+			metrics.ParseFailures.WithLabelValues("data-props").Inc()
+			fmt.Fprintf(os.Stderr, "error extracting resources from data-props: %v\n", err)
+			return
+		}
+
+		// the first page says how many there are, so queue the rest from it
+		if e.Request.URL.Query().Get("page") == "1" {
+			last := PageCount(gjson.Get(*resources, "search.data").Raw)
+			if pages > 0 && last > pages {
+				last = pages
+			}
+			for page := 2; page <= last; page++ {
+				searchCollector.Visit(URL(term, page))
+			}
+		}
+
+		productIDs, err := ToProductIDs(resources)
+		if err != nil {
+			metrics.ParseFailures.WithLabelValues("productIDs").Inc()
+			fmt.Fprintf(os.Stderr, "error extracting productIDs: %v\n", err)
+			return
+		}
+		if err := category.VisitUnfetched(productCollector, db, productIDs, stats); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	})
+	searchCollector.OnError(func(r *colly.Response, err error) {
+		stats.AddFailed()
+		fmt.Fprintln(os.Stderr, "Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
+	})
+
+	searchCollector.Visit(URL(term, 1))
+	searchCollector.Wait()
+	productCollector.Wait()
+	return nil
+}
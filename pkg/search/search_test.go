@@ -0,0 +1,48 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	got := URL("greek yoghurt", 2)
+	want := "https://www.tesco.com/groceries/en-GB/search?count=48&page=2&query=greek+yoghurt"
+	if got != want {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestPageCount(t *testing.T) {
+	tables := []struct {
+		data string
+		want int
+	}{
+		{`{"results":{"pageInformation":{"totalCount":100,"count":48}}}`, 3},
+		{`{"results":{"pageInformation":{"totalCount":48,"count":48}}}`, 1},
+		{`{"results":{"pageInformation":{"totalCount":0,"count":48}}}`, 1},
+		{`{"results":{"pageInformation":{"totalCount":97}}}`, 3},
+		{`{"results":{}}`, 1},
+	}
+	for _, tc := range tables {
+		if got := PageCount(tc.data); got != tc.want {
+			t.Errorf("got: %v, want: %v for %v", got, tc.want, tc.data)
+		}
+	}
+}
+
+func TestToProductIDs(t *testing.T) {
+	resources := `{"search":{"data":{"results":{"productItems":[{"product":{"id":"300400483"}},{"product":{"id":"300400484"}}]}}}}`
+	ids, err := ToProductIDs(&resources)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"300400483", "300400484"}; !reflect.DeepEqual(*ids, want) {
+		t.Errorf("got: %v, want: %v", *ids, want)
+	}
+
+	empty := `{"search":{"data":{}}}`
+	if _, err := ToProductIDs(&empty); err == nil {
+		t.Error("expected an error for resources without product items")
+	}
+}
@@ -0,0 +1,228 @@
+// Package similar finds substitutes for a product from the nearness of their nutrients per
+// 100g, their category and their price
+package similar
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mattburman/tesco/pkg/nutrition"
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/rank"
+)
+
+// Scope is the category substitutes must share with the product
+type Scope string
+
+const (
+	// Shelf only suggests products on the same shelf
+	Shelf Scope = "shelf"
+	// Aisle suggests products in the same aisle, nearer when on the same shelf
+	Aisle Scope = "aisle"
+	// Department suggests products in the same department
+	Department Scope = "department"
+	// Any suggests products from anywhere
+	Any Scope = "any"
+)
+
+// Scopes lists the scopes from narrowest to widest
+var Scopes = []Scope{Shelf, Aisle, Department, Any}
+
+// Prefer counts substitutes that are worse in some way as further away
+type Prefer string
+
+const (
+	// Nearest suggests the nearest products however they compare
+	Nearest Prefer = ""
+	// Cheaper counts products dearer per 100g or 100ml, or without a price, as further away
+	Cheaper Prefer = "cheaper"
+	// Protein counts products with less protein per 100g or 100ml as further away
+	Protein Prefer = "protein"
+)
+
+// Options control which substitutes are suggested
+type Options struct {
+	Scope  Scope
+	Prefer Prefer
+	// PriceWeight is how much a difference in price per 100g counts against a substitute
+	// relative to nutrients, 0 to ignore price
+	PriceWeight float64
+	Limit       int
+}
+
+// DefaultPriceWeight counts a doubling of price per 100g like a difference in one nutrient
+// of 30% of its daily reference intake, e.g. 15g of protein
+const DefaultPriceWeight = 0.3
+
+// Match is a suggested substitute. Distance is the sum of its nutrient, category, price
+// and preference distances, lower being nearer.
+type Match struct {
+	Product          *product.Product
+	Distance         float64
+	NutrientDistance float64
+	SameShelf        bool
+	// Per100 are the substitute's nutrients per 100g or 100ml
+	Per100 nutrition.Nutrients
+	// PricePer100 is nil when the unit price is unknown
+	PricePer100 *float64
+}
+
+// referenceIntakes are the UK daily reference intakes of an adult, which scale each
+// nutrient so that differences in each count alike
+var referenceIntakes = nutrition.Nutrients{Kcal: 2000, Fat: 70, Saturates: 20, Carbs: 260, Sugars: 90, Fibre: 30, Protein: 50, Salt: 6}
+
+// category distances are added to products in the same aisle on another shelf, the same
+// department in another aisle, and another department
+const (
+	shelfDistance      = 0.05
+	aisleDistance      = 0.15
+	departmentDistance = 0.3
+)
+
+// dearerDistance is added to a substitute for each doubling of price per 100g when
+// preferring cheaper ones, or once when its price is unknown
+const dearerDistance = 0.3
+
+// ErrNoNutrition is returned when the product has no nutrition per 100g or 100ml to compare
+var ErrNoNutrition = errors.New("product has no nutrition per 100g or 100ml to compare")
+
+// ErrNoPrice is returned when preferring cheaper substitutes for a product without a unit price
+var ErrNoPrice = errors.New("product has no unit price to find cheaper alternatives")
+
+// ParseScope returns the scope named s
+func ParseScope(s string) (Scope, error) {
+	for _, scope := range Scopes {
+		if strings.EqualFold(string(scope), strings.TrimSpace(s)) {
+			return scope, nil
+		}
+	}
+	return "", fmt.Errorf("unknown scope %q", s)
+}
+
+// ParsePrefer returns the preference named s, Nearest when empty
+func ParsePrefer(s string) (Prefer, error) {
+	switch prefer := Prefer(strings.ToLower(strings.TrimSpace(s))); prefer {
+	case Nearest, Cheaper, Protein:
+		return prefer, nil
+	}
+	return "", fmt.Errorf("unknown preference %q, must be %v or %v", s, Cheaper, Protein)
+}
+
+// Find returns the candidates nearest to target within opts.Scope, nearest first, with
+// those worse than target by opts.Prefer further away. Candidates without nutrition in the
+// same unit as target are left out.
+func Find(target *product.Product, candidates []*product.Product, opts Options) ([]Match, error) {
+	base, ok := per100(target)
+	if !ok {
+		return nil, ErrNoNutrition
+	}
+	basePrice, hasPrice := rank.PricePer100(target.UnitPrice(), target.UnitOfMeasure())
+	if opts.Prefer == Cheaper && (!hasPrice || basePrice <= 0) {
+		return nil, ErrNoPrice
+	}
+
+	matches := []Match{}
+	for _, p := range candidates {
+		if p.ID() == target.ID() || p.PerComp().Unit() != target.PerComp().Unit() {
+			continue
+		}
+		category, ok := categoryDistance(target, p, opts.Scope)
+		if !ok {
+			continue
+		}
+		nutrients, ok := per100(p)
+		if !ok {
+			continue
+		}
+		m := Match{Product: p, Per100: nutrients, SameShelf: sameCategory(target.Shelf(), p.Shelf())}
+		price, ok := rank.PricePer100(p.UnitPrice(), p.UnitOfMeasure())
+		if ok {
+			m.PricePer100 = &price
+		}
+
+		m.NutrientDistance = distance(base, nutrients)
+		m.Distance = m.NutrientDistance + category
+		if opts.PriceWeight > 0 && hasPrice && ok && basePrice > 0 && price > 0 {
+			m.Distance += opts.PriceWeight * math.Abs(math.Log2(price/basePrice))
+		}
+		m.Distance += preferDistance(opts.Prefer, base, basePrice, m)
+		matches = append(matches, m)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Distance < matches[j].Distance })
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+	return matches, nil
+}
+
+// preferDistance returns how much further away m is for being worse than the target by
+// prefer. Products as good or better are not moved, so nearness still ranks them.
+func preferDistance(prefer Prefer, base nutrition.Nutrients, basePrice float64, m Match) float64 {
+	switch prefer {
+	case Cheaper:
+		if m.PricePer100 == nil || *m.PricePer100 <= 0 {
+			return dearerDistance
+		}
+		return dearerDistance * math.Max(0, math.Log2(*m.PricePer100/basePrice))
+	case Protein:
+		return math.Max(0, base.Protein-m.Per100.Protein) / referenceIntakes.Protein
+	}
+	return 0
+}
+
+// per100 returns the nutrients of p per 100 of its unit, or false if it has none
+func per100(p *product.Product) (nutrition.Nutrients, bool) {
+	comp := p.PerComp()
+	scaled, ok := comp.Scale(100)
+	if !ok || (comp.Kcal() <= 0 && comp.Protein() <= 0 && comp.Carbs() <= 0 && comp.Fat() <= 0) {
+		return nutrition.Nutrients{}, false
+	}
+	return nutrition.FromMacros(scaled), true
+}
+
+// distance is the Euclidean distance between two sets of nutrients, each scaled by its
+// reference intake
+func distance(a, b nutrition.Nutrients) float64 {
+	d := func(x, y, ri float64) float64 { return (x - y) / ri }
+	sum := 0.0
+	for _, v := range []float64{
+		d(a.Kcal, b.Kcal, referenceIntakes.Kcal),
+		d(a.Fat, b.Fat, referenceIntakes.Fat),
+		d(a.Saturates, b.Saturates, referenceIntakes.Saturates),
+		d(a.Carbs, b.Carbs, referenceIntakes.Carbs),
+		d(a.Sugars, b.Sugars, referenceIntakes.Sugars),
+		d(a.Fibre, b.Fibre, referenceIntakes.Fibre),
+		d(a.Protein, b.Protein, referenceIntakes.Protein),
+		d(a.Salt, b.Salt, referenceIntakes.Salt),
+	} {
+		sum += v * v
+	}
+	return math.Sqrt(sum)
+}
+
+// categoryDistance returns how far apart the categories of a and b are, or false if b is
+// outside scope
+func categoryDistance(a, b *product.Product, scope Scope) (float64, bool) {
+	switch {
+	case sameCategory(a.Shelf(), b.Shelf()) && sameCategory(a.Aisle(), b.Aisle()):
+		return 0, true
+	case scope == Shelf:
+		return 0, false
+	case sameCategory(a.Aisle(), b.Aisle()):
+		return shelfDistance, true
+	case scope == Aisle:
+		return 0, false
+	case sameCategory(a.Department(), b.Department()):
+		return aisleDistance, true
+	case scope == Department:
+		return 0, false
+	}
+	return departmentDistance, true
+}
+
+func sameCategory(a, b string) bool {
+	return a != "" && strings.EqualFold(a, b)
+}
@@ -0,0 +1,93 @@
+package similar
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func TestFind(t *testing.T) {
+	target := `{"departmentName":"Fresh Food","aisleName":"Yoghurts","shelfName":"Greek Yoghurt","pageTitle":"Greek Style Yoghurt",` +
+		`"product":{"id":"1","unitPrice":4,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+		`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 130kcal","perServing":"-"},` +
+		`{"name":"Fat","perComp":"9g","perServing":"-"},{"name":"Protein","perComp":"10g","perServing":"-"}]}}}`
+	raws := []struct {
+		id  string
+		raw string
+	}{
+		{"1", target},
+		// the same nutrients on another shelf of the aisle, and dearer
+		{"2", `{"departmentName":"Fresh Food","aisleName":"Yoghurts","shelfName":"Natural Yoghurt","pageTitle":"Natural Yoghurt",` +
+			`"product":{"id":"2","unitPrice":8,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 130kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"9g","perServing":"-"},{"name":"Protein","perComp":"10g","perServing":"-"}]}}}`},
+		// on the same shelf, cheaper with more protein
+		{"3", `{"departmentName":"Fresh Food","aisleName":"Yoghurts","shelfName":"Greek Yoghurt","pageTitle":"High Protein Greek Yoghurt",` +
+			`"product":{"id":"3","unitPrice":3,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 120kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"8g","perServing":"-"},{"name":"Protein","perComp":"14g","perServing":"-"}]}}}`},
+		// on the same shelf, cheapest with very different nutrients
+		{"4", `{"departmentName":"Fresh Food","aisleName":"Yoghurts","shelfName":"Greek Yoghurt","pageTitle":"Fat Free Greek Yoghurt",` +
+			`"product":{"id":"4","unitPrice":2,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 60kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"0.5g","perServing":"-"},{"name":"Protein","perComp":"4g","perServing":"-"}]}}}`},
+		// another aisle with the same nutrients
+		{"5", `{"departmentName":"Fresh Food","aisleName":"Cheese","shelfName":"Cottage Cheese","pageTitle":"Cottage Cheese",` +
+			`"product":{"id":"5","unitPrice":4,"unitOfMeasure":"kg","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 130kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"9g","perServing":"-"},{"name":"Protein","perComp":"10g","perServing":"-"}]}}}`},
+		// another aisle with the same nutrients and no price
+		{"6", `{"departmentName":"Fresh Food","aisleName":"Cheese","shelfName":"Cottage Cheese","pageTitle":"Cottage Cheese Loose",` +
+			`"product":{"id":"6","details":{"nutritionInfo":[` +
+			`{"name":"Typical Values","perComp":"Per 100g","perServing":"-"},{"name":"Energy","perComp":"1kJ / 130kcal","perServing":"-"},` +
+			`{"name":"Fat","perComp":"9g","perServing":"-"},{"name":"Protein","perComp":"10g","perServing":"-"}]}}}`},
+	}
+	candidates := []*product.Product{}
+	for _, r := range raws {
+		p, err := product.NewProduct(r.raw, product.IDToURL(r.id))
+		if err != nil {
+			t.Fatalf("NewProduct() error = %v", err)
+		}
+		candidates = append(candidates, p)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		opts   Options
+		want   []string
+		err    error
+	}{
+		{"aisle", target, Options{Scope: Aisle}, []string{"2", "3", "4"}, nil},
+		{"shelf", target, Options{Scope: Shelf}, []string{"3", "4"}, nil},
+		{"department", target, Options{Scope: Department}, []string{"2", "3", "5", "6", "4"}, nil},
+		{"price", target, Options{Scope: Aisle, PriceWeight: DefaultPriceWeight}, []string{"3", "2", "4"}, nil},
+		// the cheapest product is too far from the target to outrank nearer ones that are no dearer
+		{"cheaper", target, Options{Scope: Any, Prefer: Cheaper}, []string{"3", "5", "4", "2", "6"}, nil},
+		{"cheaper limit", target, Options{Scope: Any, Prefer: Cheaper, Limit: 1}, []string{"3"}, nil},
+		{"protein", target, Options{Scope: Any, Prefer: Protein}, []string{"2", "3", "5", "6", "4"}, nil},
+		{"cheaper without a price", raws[5].raw, Options{Scope: Any, Prefer: Cheaper}, nil, ErrNoPrice},
+		{"limit", target, Options{Scope: Any, Limit: 1}, []string{"2"}, nil},
+		{"no nutrition", `{"product":{"id":"7"}}`, Options{Scope: Any}, nil, ErrNoNutrition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := product.NewProduct(tt.target, product.IDToURL("1"))
+			if err != nil {
+				t.Fatalf("NewProduct() error = %v", err)
+			}
+			matches, err := Find(p, candidates, tt.opts)
+			if err != tt.err {
+				t.Fatalf("Find() error = %v, want %v", err, tt.err)
+			}
+			got := []string{}
+			for _, m := range matches {
+				got = append(got, m.Product.ID())
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Find() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
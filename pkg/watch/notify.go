@@ -0,0 +1,92 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Notifier sends alerts
+type Notifier interface {
+	Notify(alerts []Alert) error
+}
+
+// NewNotifier returns the notifier for target: stdout when empty or "-", a webhook for
+// http and https URLs, and otherwise a file that alerts are appended to as JSON lines
+func NewNotifier(target string, stdout io.Writer) Notifier {
+	switch {
+	case target == "" || target == "-":
+		return Writer{W: stdout}
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		return Webhook{URL: target, Client: &http.Client{Timeout: 30 * time.Second}}
+	}
+	return File{Path: target}
+}
+
+// Writer writes each alert as a line of text
+type Writer struct {
+	W io.Writer
+}
+
+// Notify writes alerts to w
+func (n Writer) Notify(alerts []Alert) error {
+	for _, alert := range alerts {
+		if _, err := fmt.Fprintf(n.W, "ALERT %v\n", alert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// File appends each alert to a file as a line of JSON
+type File struct {
+	Path string
+}
+
+// Notify appends alerts to the file, creating it if needed
+func (n File) Notify(alerts []Alert) error {
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open alert file: %v", err)
+	}
+	encoder := json.NewEncoder(f)
+	for _, alert := range alerts {
+		if err := encoder.Encode(alert); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write alert: %v", err)
+		}
+	}
+	return f.Close()
+}
+
+// Webhook posts alerts to a URL as a JSON object {"alerts": [...]}
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify posts alerts to the webhook, returning an error for responses other than 2xx
+func (n Webhook) Notify(alerts []Alert) error {
+	body, err := json.Marshal(map[string][]Alert{"alerts": alerts})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alerts: %v", err)
+	}
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post alerts: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook responded %v", resp.Status)
+	}
+	return nil
+}
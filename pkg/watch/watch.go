@@ -0,0 +1,136 @@
+// Package watch evaluates price and promotion alert rules against observations of products
+// and sends the resulting alerts to stdout, a file or a webhook
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+	"github.com/mattburman/tesco/pkg/promotion"
+)
+
+// Rule is a watch on a product. When both conditions are set both must hold.
+type Rule struct {
+	ID        int64  `json:"id"`
+	ProductID string `json:"productId"`
+	// Below alerts when the best price of one item falls below it, ignored when 0
+	Below float64 `json:"below,omitempty"`
+	// OnPromo alerts when the product has an offer running
+	OnPromo   bool      `json:"onPromo"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Validate returns an error if r has no product or no condition
+func (r Rule) Validate() error {
+	if r.ProductID == "" {
+		return errors.New("a watch needs a product ID")
+	}
+	if r.Below < 0 {
+		return fmt.Errorf("price threshold %v is negative", r.Below)
+	}
+	if r.Below == 0 && !r.OnPromo {
+		return errors.New("a watch needs a price threshold, a promotion condition or both")
+	}
+	return nil
+}
+
+// String describes the conditions of r, e.g. "below £2.50 and on promotion"
+func (r Rule) String() string {
+	conditions := []string{}
+	if r.Below > 0 {
+		conditions = append(conditions, fmt.Sprintf("below £%.2f", r.Below))
+	}
+	if r.OnPromo {
+		conditions = append(conditions, "on promotion")
+	}
+	return strings.Join(conditions, " and ")
+}
+
+// Observation is the price and offers of a product when it was fetched
+type Observation struct {
+	ProductID string
+	Name      string
+	Price     float64
+	// Offers are those running at At
+	Offers []promotion.Offer
+	At     time.Time
+}
+
+// Observe returns the observation of p fetched at at
+func Observe(p *product.Product, at time.Time) Observation {
+	o := Observation{ProductID: p.ID(), Name: p.Name(), Price: p.Price(), Offers: []promotion.Offer{}, At: at}
+	for _, offer := range promotion.FromProduct(p) {
+		if offer.Active(at) {
+			o.Offers = append(o.Offers, offer)
+		}
+	}
+	return o
+}
+
+// BestPrice returns the lowest price of one item, with any offer applied, and the offer
+// giving it, or nil if the shelf price is lowest
+func (o Observation) BestPrice() (float64, *promotion.Offer) {
+	best, by := o.Price, (*promotion.Offer)(nil)
+	for i, offer := range o.Offers {
+		if price, ok := offer.EffectivePrice(o.Price); ok && price < best {
+			best, by = price, &o.Offers[i]
+		}
+	}
+	return best, by
+}
+
+// Alert is a rule met by an observation
+type Alert struct {
+	Rule      Rule      `json:"rule"`
+	ProductID string    `json:"productId"`
+	Name      string    `json:"name"`
+	Price     float64   `json:"price"`
+	BestPrice float64   `json:"bestPrice"`
+	Offers    []string  `json:"offers"`
+	Reasons   []string  `json:"reasons"`
+	At        time.Time `json:"at"`
+}
+
+// String describes the alert on a single line
+func (a Alert) String() string {
+	return fmt.Sprintf("%v %v (%v): %v", a.At.Format("2006-01-02 15:04"), a.Name, a.ProductID, strings.Join(a.Reasons, "; "))
+}
+
+// Check returns the alert for o if it meets every condition of r
+func (r Rule) Check(o Observation) (Alert, bool) {
+	best, by := o.BestPrice()
+	alert := Alert{
+		Rule:      r,
+		ProductID: o.ProductID,
+		Name:      o.Name,
+		Price:     o.Price,
+		BestPrice: best,
+		Offers:    []string{},
+		Reasons:   []string{},
+		At:        o.At,
+	}
+	for _, offer := range o.Offers {
+		alert.Offers = append(alert.Offers, offer.Text)
+	}
+
+	if r.Below > 0 {
+		if best <= 0 || best >= r.Below {
+			return Alert{}, false
+		}
+		reason := fmt.Sprintf("price £%.2f is below £%.2f", best, r.Below)
+		if by != nil {
+			reason = fmt.Sprintf("price £%.2f with %v is below £%.2f", best, by.Text, r.Below)
+		}
+		alert.Reasons = append(alert.Reasons, reason)
+	}
+	if r.OnPromo {
+		if len(o.Offers) == 0 {
+			return Alert{}, false
+		}
+		alert.Reasons = append(alert.Reasons, "on promotion: "+strings.Join(alert.Offers, ", "))
+	}
+	return alert, true
+}
@@ -0,0 +1,137 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattburman/tesco/pkg/product"
+)
+
+func observation(t *testing.T, price string, offerText string) Observation {
+	promotions := "[]"
+	if offerText != "" {
+		promotions = `[{"promotionId":"A1","promotionType":"","startDate":"2019-11-20T00:00:00.000Z","endDate":"2019-12-30T00:00:00.000Z","offerText":"` + offerText + `"}]`
+	}
+	p, err := product.NewProduct(`{"pageTitle":"Tesco Semi Skimmed Milk 2.272L","product":{"price":`+price+`},"promotions":`+promotions+`}`,
+		product.IDToURL("254918073"))
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	return Observe(p, time.Date(2019, 12, 1, 9, 0, 0, 0, time.UTC))
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        Rule
+		obs         Observation
+		want        bool
+		wantReasons int
+	}{
+		{"shelf price below", Rule{Below: 1.5}, observation(t, "1.45", ""), true, 1},
+		{"shelf price above", Rule{Below: 1.4}, observation(t, "1.45", ""), false, 0},
+		{"offer price below", Rule{Below: 1.4}, observation(t, "1.45", "£1.25 Clubcard Price"), true, 1},
+		{"on promo", Rule{OnPromo: true}, observation(t, "1.45", "Any 2 for £2.50"), true, 1},
+		{"not on promo", Rule{OnPromo: true}, observation(t, "1.45", ""), false, 0},
+		{"below and on promo", Rule{Below: 1.3, OnPromo: true}, observation(t, "1.45", "Any 2 for £2.50"), true, 2},
+		{"below but not on promo", Rule{Below: 1.5, OnPromo: true}, observation(t, "1.45", ""), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert, ok := tt.rule.Check(tt.obs)
+			if ok != tt.want || len(alert.Reasons) != tt.wantReasons {
+				t.Errorf("Check() = %+v, %v, want %v with %v reasons", alert, ok, tt.want, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestObserveIgnoresEndedOffers(t *testing.T) {
+	p, err := product.NewProduct(`{"product":{"price":1.45},"promotions":[{"promotionId":"A1","endDate":"2019-12-30T00:00:00.000Z","offerText":"Any 2 for £2.50"}]}`,
+		product.IDToURL("254918073"))
+	if err != nil {
+		t.Fatalf("NewProduct() error = %v", err)
+	}
+	if got := Observe(p, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)); len(got.Offers) != 0 {
+		t.Errorf("Observe() offers = %+v, want none", got.Offers)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		rule    Rule
+		wantErr bool
+	}{
+		{Rule{ProductID: "254918073", Below: 2.5}, false},
+		{Rule{ProductID: "254918073", OnPromo: true}, false},
+		{Rule{ProductID: "254918073"}, true},
+		{Rule{ProductID: "254918073", Below: -1}, true},
+		{Rule{Below: 2.5}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	alert, _ := Rule{ID: 1, ProductID: "254918073", Below: 1.5}.Check(observation(t, "1.45", ""))
+	alerts := []Alert{alert}
+
+	var buf bytes.Buffer
+	if err := NewNotifier("", &buf).Notify(alerts); err != nil {
+		t.Fatalf("Writer.Notify() error = %v", err)
+	}
+	if want := "ALERT 2019-12-01 09:00 Tesco Semi Skimmed Milk 2.272L (254918073): price £1.45 is below £1.50\n"; buf.String() != want {
+		t.Errorf("Writer.Notify() wrote %q, want %q", buf.String(), want)
+	}
+
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "alerts.jsonl")
+	for i := 0; i < 2; i++ {
+		if err := NewNotifier(path, nil).Notify(alerts); err != nil {
+			t.Fatalf("File.Notify() error = %v", err)
+		}
+	}
+	b, _ := ioutil.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"productId":"254918073"`) {
+		t.Errorf("File.Notify() wrote %q", b)
+	}
+
+	var received struct{ Alerts []Alert }
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+	if err := NewNotifier(server.URL, nil).Notify(alerts); err != nil {
+		t.Fatalf("Webhook.Notify() error = %v", err)
+	}
+	if len(received.Alerts) != 1 || received.Alerts[0].BestPrice != 1.45 || received.Alerts[0].Rule.ID != 1 {
+		t.Errorf("Webhook.Notify() posted %+v", received)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := NewNotifier(failing.URL, nil).Notify(alerts); err == nil {
+		t.Error("Webhook.Notify() expected an error for a 500 response")
+	}
+}